@@ -12,6 +12,9 @@ import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	anypb "google.golang.org/protobuf/types/known/anypb"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -176,6 +179,7 @@ type PostUnstructuredDataRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	Data          *anypb.Any             `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -224,10 +228,18 @@ func (x *PostUnstructuredDataRequest) GetData() *anypb.Any {
 	return nil
 }
 
+func (x *PostUnstructuredDataRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
 type PostUnstructuredDataResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	Data          *anypb.Any             `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -276,36 +288,443 @@ func (x *PostUnstructuredDataResponse) GetData() *anypb.Any {
 	return nil
 }
 
+func (x *PostUnstructuredDataResponse) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type UnstructuredData struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Data          *anypb.Any             `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	CreateTime    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=create_time,json=createTime,proto3" json:"create_time,omitempty"`
+	UpdateTime    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=update_time,json=updateTime,proto3" json:"update_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnstructuredData) Reset() {
+	*x = UnstructuredData{}
+	mi := &file_pb_discover_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnstructuredData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnstructuredData) ProtoMessage() {}
+
+func (x *UnstructuredData) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_discover_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnstructuredData.ProtoReflect.Descriptor instead.
+func (*UnstructuredData) Descriptor() ([]byte, []int) {
+	return file_pb_discover_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UnstructuredData) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UnstructuredData) GetData() *anypb.Any {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *UnstructuredData) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *UnstructuredData) GetCreateTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreateTime
+	}
+	return nil
+}
+
+func (x *UnstructuredData) GetUpdateTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdateTime
+	}
+	return nil
+}
+
+type GetUnstructuredDataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ReadMask      *fieldmaskpb.FieldMask `protobuf:"bytes,2,opt,name=read_mask,json=readMask,proto3" json:"read_mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUnstructuredDataRequest) Reset() {
+	*x = GetUnstructuredDataRequest{}
+	mi := &file_pb_discover_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUnstructuredDataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUnstructuredDataRequest) ProtoMessage() {}
+
+func (x *GetUnstructuredDataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_discover_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUnstructuredDataRequest.ProtoReflect.Descriptor instead.
+func (*GetUnstructuredDataRequest) Descriptor() ([]byte, []int) {
+	return file_pb_discover_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetUnstructuredDataRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetUnstructuredDataRequest) GetReadMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.ReadMask
+	}
+	return nil
+}
+
+type ListUnstructuredDataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageSize      int32                  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken     string                 `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUnstructuredDataRequest) Reset() {
+	*x = ListUnstructuredDataRequest{}
+	mi := &file_pb_discover_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUnstructuredDataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUnstructuredDataRequest) ProtoMessage() {}
+
+func (x *ListUnstructuredDataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_discover_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUnstructuredDataRequest.ProtoReflect.Descriptor instead.
+func (*ListUnstructuredDataRequest) Descriptor() ([]byte, []int) {
+	return file_pb_discover_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListUnstructuredDataRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListUnstructuredDataRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListUnstructuredDataResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	UnstructuredData []*UnstructuredData    `protobuf:"bytes,1,rep,name=unstructured_data,json=unstructuredData,proto3" json:"unstructured_data,omitempty"`
+	NextPageToken    string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ListUnstructuredDataResponse) Reset() {
+	*x = ListUnstructuredDataResponse{}
+	mi := &file_pb_discover_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUnstructuredDataResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUnstructuredDataResponse) ProtoMessage() {}
+
+func (x *ListUnstructuredDataResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_discover_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUnstructuredDataResponse.ProtoReflect.Descriptor instead.
+func (*ListUnstructuredDataResponse) Descriptor() ([]byte, []int) {
+	return file_pb_discover_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListUnstructuredDataResponse) GetUnstructuredData() []*UnstructuredData {
+	if x != nil {
+		return x.UnstructuredData
+	}
+	return nil
+}
+
+func (x *ListUnstructuredDataResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type UpdateUnstructuredDataRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UnstructuredData *UnstructuredData      `protobuf:"bytes,2,opt,name=unstructured_data,json=unstructuredData,proto3" json:"unstructured_data,omitempty"`
+	UpdateMask       *fieldmaskpb.FieldMask `protobuf:"bytes,3,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *UpdateUnstructuredDataRequest) Reset() {
+	*x = UpdateUnstructuredDataRequest{}
+	mi := &file_pb_discover_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateUnstructuredDataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateUnstructuredDataRequest) ProtoMessage() {}
+
+func (x *UpdateUnstructuredDataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_discover_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateUnstructuredDataRequest.ProtoReflect.Descriptor instead.
+func (*UpdateUnstructuredDataRequest) Descriptor() ([]byte, []int) {
+	return file_pb_discover_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UpdateUnstructuredDataRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateUnstructuredDataRequest) GetUnstructuredData() *UnstructuredData {
+	if x != nil {
+		return x.UnstructuredData
+	}
+	return nil
+}
+
+func (x *UpdateUnstructuredDataRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+type DeleteUnstructuredDataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteUnstructuredDataRequest) Reset() {
+	*x = DeleteUnstructuredDataRequest{}
+	mi := &file_pb_discover_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteUnstructuredDataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteUnstructuredDataRequest) ProtoMessage() {}
+
+func (x *DeleteUnstructuredDataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_discover_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteUnstructuredDataRequest.ProtoReflect.Descriptor instead.
+func (*DeleteUnstructuredDataRequest) Descriptor() ([]byte, []int) {
+	return file_pb_discover_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteUnstructuredDataRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
 var File_pb_discover_proto protoreflect.FileDescriptor
 
 const file_pb_discover_proto_rawDesc = "" +
 	"\n" +
-	"\x11pb/discover.proto\x12\x11discoverservicepb\x1a\x1cgoogle/api/annotations.proto\x1a\x19google/protobuf/any.proto\x1a.protoc-gen-openapiv2/options/annotations.proto\"*\n" +
+	"\x11pb/discover.proto\x12\x11discoverservicepb\x1a\x1cgoogle/api/annotations.proto\x1a\x19google/protobuf/any.proto\x1a\x1bgoogle/protobuf/empty.proto\x1a google/protobuf/field_mask.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x10pb/options.proto\x1a.protoc-gen-openapiv2/options/annotations.proto\"*\n" +
 	"\bResponse\x12\x1e\n" +
 	"\n" +
 	"newContent\x18\x01 \x01(\tR\n" +
 	"newContent\"\x87\x01\n" +
 	"\x15GetParamInBodyRequest\x126\n" +
 	"\x02id\x18\x01 \x01(\tB&\x92A#2!Unique identifier for the requestR\x02id\x126\n" +
-	"\acontent\x18\x02 \x01(\tB\x1c\x92A\x192\x17Content to be processedR\acontent\"\x89\x01\n" +
-	"\x17GetParamInHeaderRequest\x126\n" +
-	"\x02id\x18\x01 \x01(\tB&\x92A#2!Unique identifier for the requestR\x02id\x126\n" +
-	"\acontent\x18\x02 \x01(\tB\x1c\x92A\x192\x17Content to be processedR\acontent\"\xa1\x01\n" +
+	"\acontent\x18\x02 \x01(\tB\x1c\x92A\x192\x17Content to be processedR\acontent\"\xa3\x01\n" +
+	"\x17GetParamInHeaderRequest\x12P\n" +
+	"\x02id\x18\x01 \x01(\tB@\x92A#2!Unique identifier for the request\xaa\xbb\x18\x16\n" +
+	"\x12X-Custom-Header-Id\x10\x01R\x02id\x126\n" +
+	"\acontent\x18\x02 \x01(\tB\x1c\x92A\x192\x17Content to be processedR\acontent\"\xd2\x02\n" +
 	"\x1bPostUnstructuredDataRequest\x123\n" +
 	"\x02id\x18\x01 \x01(\tB#\x92A 2\x1eUnique identifier for the dataR\x02id\x12M\n" +
-	"\x04data\x18\x02 \x01(\v2\x14.google.protobuf.AnyB#\x92A 2\x1eUnstructured data to be postedR\x04data\"\x95\x01\n" +
+	"\x04data\x18\x02 \x01(\v2\x14.google.protobuf.AnyB#\x92A 2\x1eUnstructured data to be postedR\x04data\x12t\n" +
+	"\x06labels\x18\x03 \x03(\v2:.discoverservicepb.PostUnstructuredDataRequest.LabelsEntryB \x92A\x1d2\x1bLabels attached to the dataR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xc7\x02\n" +
 	"\x1cPostUnstructuredDataResponse\x127\n" +
 	"\x02id\x18\x01 \x01(\tB'\x92A$2\"Unique identifier for the responseR\x02id\x12<\n" +
-	"\x04data\x18\x02 \x01(\v2\x14.google.protobuf.AnyB\x12\x92A\x0f2\rResponse dataR\x04data2\xb8\x05\n" +
-	"\x0fDiscoverService\x12\xd8\x01\n" +
-	"\x0eGetParamInBody\x12(.discoverservicepb.GetParamInBodyRequest\x1a\x1b.discoverservicepb.Response\"\x7f\x92AZ\n" +
+	"\x04data\x18\x02 \x01(\v2\x14.google.protobuf.AnyB\x12\x92A\x0f2\rResponse dataR\x04data\x12u\n" +
+	"\x06labels\x18\x03 \x03(\v2;.discoverservicepb.PostUnstructuredDataResponse.LabelsEntryB \x92A\x1d2\x1bLabels attached to the dataR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xf8\x03\n" +
+	"\x10UnstructuredData\x123\n" +
+	"\x02id\x18\x01 \x01(\tB#\x92A 2\x1eUnique identifier for the dataR\x02id\x12G\n" +
+	"\x04data\x18\x02 \x01(\v2\x14.google.protobuf.AnyB\x1d\x92A\x1a2\x18Stored unstructured dataR\x04data\x12i\n" +
+	"\x06labels\x18\x03 \x03(\v2/.discoverservicepb.UnstructuredData.LabelsEntryB \x92A\x1d2\x1bLabels attached to the dataR\x06labels\x12\\\n" +
+	"\vcreate_time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampB\x1f\x92A\x1c2\x18When the data was posted@\x01R\n" +
+	"createTime\x12b\n" +
+	"\vupdate_time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampB%\x92A\"2\x1eWhen the data was last updated@\x01R\n" +
+	"updateTime\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xdf\x01\n" +
+	"\x1aGetUnstructuredDataRequest\x12:\n" +
+	"\x02id\x18\x01 \x01(\tB*\x92A'2%Unique identifier of the data to readR\x02id\x12\x84\x01\n" +
+	"\tread_mask\x18\x02 \x01(\v2\x1a.google.protobuf.FieldMaskBK\x92AH2FFields to return, e.g. \"id,labels\". All fields are returned when emptyR\breadMask\"\x85\x02\n" +
+	"\x1bListUnstructuredDataRequest\x12z\n" +
+	"\tpage_size\x18\x01 \x01(\x05B]\x92AZ2XMaximum number of items to return. Defaults to 50; values above 1000 are coerced to 1000R\bpageSize\x12j\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tBK\x92AH2Fnext_page_token of a previous response, to retrieve the following pageR\tpageToken\"\x8a\x02\n" +
+	"\x1cListUnstructuredDataResponse\x12}\n" +
+	"\x11unstructured_data\x18\x01 \x03(\v2#.discoverservicepb.UnstructuredDataB+\x92A(2&Stored unstructured data ordered by idR\x10unstructuredData\x12k\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tBC\x92A@2>Opaque token to retrieve the next page; empty on the last pageR\rnextPageToken\"\x8a\x03\n" +
+	"\x1dUpdateUnstructuredDataRequest\x12<\n" +
+	"\x02id\x18\x01 \x01(\tB,\x92A)2'Unique identifier of the data to updateR\x02id\x12\x86\x01\n" +
+	"\x11unstructured_data\x18\x02 \x01(\v2#.discoverservicepb.UnstructuredDataB4\x92A12/New values for the fields listed in update_maskR\x10unstructuredData\x12\xa1\x01\n" +
+	"\vupdate_mask\x18\x03 \x01(\v2\x1a.google.protobuf.FieldMaskBd\x92Aa2_Fields to overwrite: data and/or labels, or \"*\" for all. Inferred from the JSON body when emptyR\n" +
+	"updateMask\"]\n" +
+	"\x1dDeleteUnstructuredDataRequest\x12<\n" +
+	"\x02id\x18\x01 \x01(\tB,\x92A)2'Unique identifier of the data to deleteR\x02id2\x9f\x0f\n" +
+	"\x0fDiscoverService\x12\x83\x02\n" +
+	"\x0eGetParamInBody\x12(.discoverservicepb.GetParamInBodyRequest\x1a\x1b.discoverservicepb.Response\"\xa9\x01\x92AZ\n" +
 	"\n" +
-	"Parameters\x12\x15Get parameter in body\x1a5Retrieves parameter information from the request body\x82\xd3\xe4\x93\x02\x1c\x12\x1a/v1/get-param-in-body/{id}\x12\xdf\x01\n" +
-	"\x10GetParamInHeader\x12*.discoverservicepb.GetParamInHeaderRequest\x1a\x1b.discoverservicepb.Response\"\x81\x01\x92A_\n" +
+	"Parameters\x12\x15Get parameter in body\x1a5Retrieves parameter information from the request body\xa2\xbb\x18&\n" +
+	"\x06reader\n" +
+	"\x06writer\n" +
+	"\x05admin\x12\rdiscover.read\x82\xd3\xe4\x93\x02\x1c\x12\x1a/v1/get-param-in-body/{id}\x12\xc1\x02\n" +
+	"\x10GetParamInHeader\x12*.discoverservicepb.GetParamInHeaderRequest\x1a\x1b.discoverservicepb.Response\"\xe3\x01\x92A\x96\x01\n" +
 	"\n" +
-	"Parameters\x12\x17Get parameter in header\x1a8Retrieves parameter information from the request headers\x82\xd3\xe4\x93\x02\x19\x12\x17/v1/get-param-in-header\x12\xe7\x01\n" +
-	"\x14PostUnstructuredData\x12..discoverservicepb.PostUnstructuredDataRequest\x1a/.discoverservicepb.PostUnstructuredDataResponse\"n\x92AF\n" +
-	"\x04Data\x12\x16Post unstructured data\x1a&Posts unstructured data to the service\x82\xd3\xe4\x93\x02\x1f:\x01*\"\x1a/v1/post/unstructured-dataB\xaf\x01\x92A\x97\x01\x12m\n" +
+	"Parameters\x12\x17Get parameter in header\x1a8Retrieves parameter information from the request headersr5\n" +
+	"3\n" +
+	"\x12X-Custom-Header-Id\x12\x19Custom header for data id\x18\x01(\x01\xa2\xbb\x18&\n" +
+	"\x06reader\n" +
+	"\x06writer\n" +
+	"\x05admin\x12\rdiscover.read\x82\xd3\xe4\x93\x02\x19\x12\x17/v1/get-param-in-header\x12\x8b\x02\n" +
+	"\x14PostUnstructuredData\x12..discoverservicepb.PostUnstructuredDataRequest\x1a/.discoverservicepb.PostUnstructuredDataResponse\"\x91\x01\x92AF\n" +
+	"\x04Data\x12\x16Post unstructured data\x1a&Posts unstructured data to the service\xa2\xbb\x18\x1f\n" +
+	"\x06writer\n" +
+	"\x05admin\x12\x0ediscover.write\x82\xd3\xe4\x93\x02\x1f:\x01*\"\x1a/v1/post/unstructured-data\x12\x88\x02\n" +
+	"\x13GetUnstructuredData\x12-.discoverservicepb.GetUnstructuredDataRequest\x1a#.discoverservicepb.UnstructuredData\"\x9c\x01\x92AM\n" +
+	"\x04Data\x12\x15Get unstructured data\x1a.Reads back previously posted unstructured data\xa2\xbb\x18&\n" +
+	"\x06reader\n" +
+	"\x06writer\n" +
+	"\x05admin\x12\rdiscover.read\x82\xd3\xe4\x93\x02\x1c\x12\x1a/v1/unstructured-data/{id}\x12\x94\x02\n" +
+	"\x14ListUnstructuredData\x12..discoverservicepb.ListUnstructuredDataRequest\x1a/.discoverservicepb.ListUnstructuredDataResponse\"\x9a\x01\x92AP\n" +
+	"\x04Data\x12\x16List unstructured data\x1a0Lists the stored unstructured data ordered by id\xa2\xbb\x18&\n" +
+	"\x06reader\n" +
+	"\x06writer\n" +
+	"\x05admin\x12\rdiscover.read\x82\xd3\xe4\x93\x02\x17\x12\x15/v1/unstructured-data\x12\xa6\x02\n" +
+	"\x16UpdateUnstructuredData\x120.discoverservicepb.UpdateUnstructuredDataRequest\x1a#.discoverservicepb.UnstructuredData\"\xb4\x01\x92AY\n" +
+	"\x04Data\x12\x18Update unstructured data\x1a7Updates the data and labels of stored unstructured data\xa2\xbb\x18\x1f\n" +
+	"\x06writer\n" +
+	"\x05admin\x12\x0ediscover.write\x82\xd3\xe4\x93\x02/:\x11unstructured_data2\x1a/v1/unstructured-data/{id}\x12\xe8\x01\n" +
+	"\x16DeleteUnstructuredData\x120.discoverservicepb.DeleteUnstructuredDataRequest\x1a\x16.google.protobuf.Empty\"\x83\x01\x92AB\n" +
+	"\x04Data\x12\x18Delete unstructured data\x1a Deletes stored unstructured data\xa2\xbb\x18\x18\n" +
+	"\x05admin\x12\x0fdiscover.delete\x82\xd3\xe4\x93\x02\x1c*\x1a/v1/unstructured-data/{id}B\xaf\x01\x92A\x97\x01\x12m\n" +
 	"\x14Discover Service API\x12#API for discover service operations\"+\n" +
 	"\vAPI Support\x12\x1chttps://github.com/your-repo2\x031.0*\x02\x01\x022\x10application/json:\x10application/jsonZ\x12/discoverservicepbb\x06proto3"
 
@@ -321,29 +740,59 @@ func file_pb_discover_proto_rawDescGZIP() []byte {
 	return file_pb_discover_proto_rawDescData
 }
 
-var file_pb_discover_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_pb_discover_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_pb_discover_proto_goTypes = []any{
-	(*Response)(nil),                     // 0: discoverservicepb.Response
-	(*GetParamInBodyRequest)(nil),        // 1: discoverservicepb.GetParamInBodyRequest
-	(*GetParamInHeaderRequest)(nil),      // 2: discoverservicepb.GetParamInHeaderRequest
-	(*PostUnstructuredDataRequest)(nil),  // 3: discoverservicepb.PostUnstructuredDataRequest
-	(*PostUnstructuredDataResponse)(nil), // 4: discoverservicepb.PostUnstructuredDataResponse
-	(*anypb.Any)(nil),                    // 5: google.protobuf.Any
+	(*Response)(nil),                      // 0: discoverservicepb.Response
+	(*GetParamInBodyRequest)(nil),         // 1: discoverservicepb.GetParamInBodyRequest
+	(*GetParamInHeaderRequest)(nil),       // 2: discoverservicepb.GetParamInHeaderRequest
+	(*PostUnstructuredDataRequest)(nil),   // 3: discoverservicepb.PostUnstructuredDataRequest
+	(*PostUnstructuredDataResponse)(nil),  // 4: discoverservicepb.PostUnstructuredDataResponse
+	(*UnstructuredData)(nil),              // 5: discoverservicepb.UnstructuredData
+	(*GetUnstructuredDataRequest)(nil),    // 6: discoverservicepb.GetUnstructuredDataRequest
+	(*ListUnstructuredDataRequest)(nil),   // 7: discoverservicepb.ListUnstructuredDataRequest
+	(*ListUnstructuredDataResponse)(nil),  // 8: discoverservicepb.ListUnstructuredDataResponse
+	(*UpdateUnstructuredDataRequest)(nil), // 9: discoverservicepb.UpdateUnstructuredDataRequest
+	(*DeleteUnstructuredDataRequest)(nil), // 10: discoverservicepb.DeleteUnstructuredDataRequest
+	nil,                                   // 11: discoverservicepb.PostUnstructuredDataRequest.LabelsEntry
+	nil,                                   // 12: discoverservicepb.PostUnstructuredDataResponse.LabelsEntry
+	nil,                                   // 13: discoverservicepb.UnstructuredData.LabelsEntry
+	(*anypb.Any)(nil),                     // 14: google.protobuf.Any
+	(*timestamppb.Timestamp)(nil),         // 15: google.protobuf.Timestamp
+	(*fieldmaskpb.FieldMask)(nil),         // 16: google.protobuf.FieldMask
+	(*emptypb.Empty)(nil),                 // 17: google.protobuf.Empty
 }
 var file_pb_discover_proto_depIdxs = []int32{
-	5, // 0: discoverservicepb.PostUnstructuredDataRequest.data:type_name -> google.protobuf.Any
-	5, // 1: discoverservicepb.PostUnstructuredDataResponse.data:type_name -> google.protobuf.Any
-	1, // 2: discoverservicepb.DiscoverService.GetParamInBody:input_type -> discoverservicepb.GetParamInBodyRequest
-	2, // 3: discoverservicepb.DiscoverService.GetParamInHeader:input_type -> discoverservicepb.GetParamInHeaderRequest
-	3, // 4: discoverservicepb.DiscoverService.PostUnstructuredData:input_type -> discoverservicepb.PostUnstructuredDataRequest
-	0, // 5: discoverservicepb.DiscoverService.GetParamInBody:output_type -> discoverservicepb.Response
-	0, // 6: discoverservicepb.DiscoverService.GetParamInHeader:output_type -> discoverservicepb.Response
-	4, // 7: discoverservicepb.DiscoverService.PostUnstructuredData:output_type -> discoverservicepb.PostUnstructuredDataResponse
-	5, // [5:8] is the sub-list for method output_type
-	2, // [2:5] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	14, // 0: discoverservicepb.PostUnstructuredDataRequest.data:type_name -> google.protobuf.Any
+	11, // 1: discoverservicepb.PostUnstructuredDataRequest.labels:type_name -> discoverservicepb.PostUnstructuredDataRequest.LabelsEntry
+	14, // 2: discoverservicepb.PostUnstructuredDataResponse.data:type_name -> google.protobuf.Any
+	12, // 3: discoverservicepb.PostUnstructuredDataResponse.labels:type_name -> discoverservicepb.PostUnstructuredDataResponse.LabelsEntry
+	14, // 4: discoverservicepb.UnstructuredData.data:type_name -> google.protobuf.Any
+	13, // 5: discoverservicepb.UnstructuredData.labels:type_name -> discoverservicepb.UnstructuredData.LabelsEntry
+	15, // 6: discoverservicepb.UnstructuredData.create_time:type_name -> google.protobuf.Timestamp
+	15, // 7: discoverservicepb.UnstructuredData.update_time:type_name -> google.protobuf.Timestamp
+	16, // 8: discoverservicepb.GetUnstructuredDataRequest.read_mask:type_name -> google.protobuf.FieldMask
+	5,  // 9: discoverservicepb.ListUnstructuredDataResponse.unstructured_data:type_name -> discoverservicepb.UnstructuredData
+	5,  // 10: discoverservicepb.UpdateUnstructuredDataRequest.unstructured_data:type_name -> discoverservicepb.UnstructuredData
+	16, // 11: discoverservicepb.UpdateUnstructuredDataRequest.update_mask:type_name -> google.protobuf.FieldMask
+	1,  // 12: discoverservicepb.DiscoverService.GetParamInBody:input_type -> discoverservicepb.GetParamInBodyRequest
+	2,  // 13: discoverservicepb.DiscoverService.GetParamInHeader:input_type -> discoverservicepb.GetParamInHeaderRequest
+	3,  // 14: discoverservicepb.DiscoverService.PostUnstructuredData:input_type -> discoverservicepb.PostUnstructuredDataRequest
+	6,  // 15: discoverservicepb.DiscoverService.GetUnstructuredData:input_type -> discoverservicepb.GetUnstructuredDataRequest
+	7,  // 16: discoverservicepb.DiscoverService.ListUnstructuredData:input_type -> discoverservicepb.ListUnstructuredDataRequest
+	9,  // 17: discoverservicepb.DiscoverService.UpdateUnstructuredData:input_type -> discoverservicepb.UpdateUnstructuredDataRequest
+	10, // 18: discoverservicepb.DiscoverService.DeleteUnstructuredData:input_type -> discoverservicepb.DeleteUnstructuredDataRequest
+	0,  // 19: discoverservicepb.DiscoverService.GetParamInBody:output_type -> discoverservicepb.Response
+	0,  // 20: discoverservicepb.DiscoverService.GetParamInHeader:output_type -> discoverservicepb.Response
+	4,  // 21: discoverservicepb.DiscoverService.PostUnstructuredData:output_type -> discoverservicepb.PostUnstructuredDataResponse
+	5,  // 22: discoverservicepb.DiscoverService.GetUnstructuredData:output_type -> discoverservicepb.UnstructuredData
+	8,  // 23: discoverservicepb.DiscoverService.ListUnstructuredData:output_type -> discoverservicepb.ListUnstructuredDataResponse
+	5,  // 24: discoverservicepb.DiscoverService.UpdateUnstructuredData:output_type -> discoverservicepb.UnstructuredData
+	17, // 25: discoverservicepb.DiscoverService.DeleteUnstructuredData:output_type -> google.protobuf.Empty
+	19, // [19:26] is the sub-list for method output_type
+	12, // [12:19] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
 }
 
 func init() { file_pb_discover_proto_init() }
@@ -351,13 +800,14 @@ func file_pb_discover_proto_init() {
 	if File_pb_discover_proto != nil {
 		return
 	}
+	file_pb_options_proto_init()
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pb_discover_proto_rawDesc), len(file_pb_discover_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -11,6 +11,7 @@ import (
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 // This is a compile-time assertion to ensure that this generated file
@@ -19,9 +20,13 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	DiscoverService_GetParamInBody_FullMethodName       = "/discoverservicepb.DiscoverService/GetParamInBody"
-	DiscoverService_GetParamInHeader_FullMethodName     = "/discoverservicepb.DiscoverService/GetParamInHeader"
-	DiscoverService_PostUnstructuredData_FullMethodName = "/discoverservicepb.DiscoverService/PostUnstructuredData"
+	DiscoverService_GetParamInBody_FullMethodName         = "/discoverservicepb.DiscoverService/GetParamInBody"
+	DiscoverService_GetParamInHeader_FullMethodName       = "/discoverservicepb.DiscoverService/GetParamInHeader"
+	DiscoverService_PostUnstructuredData_FullMethodName   = "/discoverservicepb.DiscoverService/PostUnstructuredData"
+	DiscoverService_GetUnstructuredData_FullMethodName    = "/discoverservicepb.DiscoverService/GetUnstructuredData"
+	DiscoverService_ListUnstructuredData_FullMethodName   = "/discoverservicepb.DiscoverService/ListUnstructuredData"
+	DiscoverService_UpdateUnstructuredData_FullMethodName = "/discoverservicepb.DiscoverService/UpdateUnstructuredData"
+	DiscoverService_DeleteUnstructuredData_FullMethodName = "/discoverservicepb.DiscoverService/DeleteUnstructuredData"
 )
 
 // DiscoverServiceClient is the client API for DiscoverService service.
@@ -32,6 +37,10 @@ type DiscoverServiceClient interface {
 	GetParamInHeader(ctx context.Context, in *GetParamInHeaderRequest, opts ...grpc.CallOption) (*Response, error)
 	// Sends another greeting
 	PostUnstructuredData(ctx context.Context, in *PostUnstructuredDataRequest, opts ...grpc.CallOption) (*PostUnstructuredDataResponse, error)
+	GetUnstructuredData(ctx context.Context, in *GetUnstructuredDataRequest, opts ...grpc.CallOption) (*UnstructuredData, error)
+	ListUnstructuredData(ctx context.Context, in *ListUnstructuredDataRequest, opts ...grpc.CallOption) (*ListUnstructuredDataResponse, error)
+	UpdateUnstructuredData(ctx context.Context, in *UpdateUnstructuredDataRequest, opts ...grpc.CallOption) (*UnstructuredData, error)
+	DeleteUnstructuredData(ctx context.Context, in *DeleteUnstructuredDataRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type discoverServiceClient struct {
@@ -72,6 +81,46 @@ func (c *discoverServiceClient) PostUnstructuredData(ctx context.Context, in *Po
 	return out, nil
 }
 
+func (c *discoverServiceClient) GetUnstructuredData(ctx context.Context, in *GetUnstructuredDataRequest, opts ...grpc.CallOption) (*UnstructuredData, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnstructuredData)
+	err := c.cc.Invoke(ctx, DiscoverService_GetUnstructuredData_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoverServiceClient) ListUnstructuredData(ctx context.Context, in *ListUnstructuredDataRequest, opts ...grpc.CallOption) (*ListUnstructuredDataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUnstructuredDataResponse)
+	err := c.cc.Invoke(ctx, DiscoverService_ListUnstructuredData_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoverServiceClient) UpdateUnstructuredData(ctx context.Context, in *UpdateUnstructuredDataRequest, opts ...grpc.CallOption) (*UnstructuredData, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnstructuredData)
+	err := c.cc.Invoke(ctx, DiscoverService_UpdateUnstructuredData_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoverServiceClient) DeleteUnstructuredData(ctx context.Context, in *DeleteUnstructuredDataRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, DiscoverService_DeleteUnstructuredData_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DiscoverServiceServer is the server API for DiscoverService service.
 // All implementations must embed UnimplementedDiscoverServiceServer
 // for forward compatibility.
@@ -80,6 +129,10 @@ type DiscoverServiceServer interface {
 	GetParamInHeader(context.Context, *GetParamInHeaderRequest) (*Response, error)
 	// Sends another greeting
 	PostUnstructuredData(context.Context, *PostUnstructuredDataRequest) (*PostUnstructuredDataResponse, error)
+	GetUnstructuredData(context.Context, *GetUnstructuredDataRequest) (*UnstructuredData, error)
+	ListUnstructuredData(context.Context, *ListUnstructuredDataRequest) (*ListUnstructuredDataResponse, error)
+	UpdateUnstructuredData(context.Context, *UpdateUnstructuredDataRequest) (*UnstructuredData, error)
+	DeleteUnstructuredData(context.Context, *DeleteUnstructuredDataRequest) (*emptypb.Empty, error)
 	mustEmbedUnimplementedDiscoverServiceServer()
 }
 
@@ -99,6 +152,18 @@ func (UnimplementedDiscoverServiceServer) GetParamInHeader(context.Context, *Get
 func (UnimplementedDiscoverServiceServer) PostUnstructuredData(context.Context, *PostUnstructuredDataRequest) (*PostUnstructuredDataResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PostUnstructuredData not implemented")
 }
+func (UnimplementedDiscoverServiceServer) GetUnstructuredData(context.Context, *GetUnstructuredDataRequest) (*UnstructuredData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUnstructuredData not implemented")
+}
+func (UnimplementedDiscoverServiceServer) ListUnstructuredData(context.Context, *ListUnstructuredDataRequest) (*ListUnstructuredDataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUnstructuredData not implemented")
+}
+func (UnimplementedDiscoverServiceServer) UpdateUnstructuredData(context.Context, *UpdateUnstructuredDataRequest) (*UnstructuredData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUnstructuredData not implemented")
+}
+func (UnimplementedDiscoverServiceServer) DeleteUnstructuredData(context.Context, *DeleteUnstructuredDataRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUnstructuredData not implemented")
+}
 func (UnimplementedDiscoverServiceServer) mustEmbedUnimplementedDiscoverServiceServer() {}
 func (UnimplementedDiscoverServiceServer) testEmbeddedByValue()                         {}
 
@@ -174,6 +239,78 @@ func _DiscoverService_PostUnstructuredData_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DiscoverService_GetUnstructuredData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUnstructuredDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoverServiceServer).GetUnstructuredData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoverService_GetUnstructuredData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoverServiceServer).GetUnstructuredData(ctx, req.(*GetUnstructuredDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DiscoverService_ListUnstructuredData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUnstructuredDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoverServiceServer).ListUnstructuredData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoverService_ListUnstructuredData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoverServiceServer).ListUnstructuredData(ctx, req.(*ListUnstructuredDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DiscoverService_UpdateUnstructuredData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUnstructuredDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoverServiceServer).UpdateUnstructuredData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoverService_UpdateUnstructuredData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoverServiceServer).UpdateUnstructuredData(ctx, req.(*UpdateUnstructuredDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DiscoverService_DeleteUnstructuredData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUnstructuredDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoverServiceServer).DeleteUnstructuredData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoverService_DeleteUnstructuredData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoverServiceServer).DeleteUnstructuredData(ctx, req.(*DeleteUnstructuredDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DiscoverService_ServiceDesc is the grpc.ServiceDesc for DiscoverService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -193,6 +330,22 @@ var DiscoverService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PostUnstructuredData",
 			Handler:    _DiscoverService_PostUnstructuredData_Handler,
 		},
+		{
+			MethodName: "GetUnstructuredData",
+			Handler:    _DiscoverService_GetUnstructuredData_Handler,
+		},
+		{
+			MethodName: "ListUnstructuredData",
+			Handler:    _DiscoverService_ListUnstructuredData_Handler,
+		},
+		{
+			MethodName: "UpdateUnstructuredData",
+			Handler:    _DiscoverService_UpdateUnstructuredData_Handler,
+		},
+		{
+			MethodName: "DeleteUnstructuredData",
+			Handler:    _DiscoverService_DeleteUnstructuredData_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb/discover.proto",
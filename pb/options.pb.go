@@ -0,0 +1,242 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: pb/options.proto
+
+package discoverservicepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// AuthorizationRule declares who may call an RPC.
+// The caller must hold at least one of the roles or every one of the scopes.
+type AuthorizationRule struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Roles granting access, read from the "roles" claim of the bearer token
+	Roles []string `protobuf:"bytes,1,rep,name=roles,proto3" json:"roles,omitempty"`
+	// Scopes required for access, read from the "scope" (space separated) or "scp" claim
+	Scopes        []string `protobuf:"bytes,2,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthorizationRule) Reset() {
+	*x = AuthorizationRule{}
+	mi := &file_pb_options_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthorizationRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthorizationRule) ProtoMessage() {}
+
+func (x *AuthorizationRule) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_options_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthorizationRule.ProtoReflect.Descriptor instead.
+func (*AuthorizationRule) Descriptor() ([]byte, []int) {
+	return file_pb_options_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AuthorizationRule) GetRoles() []string {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+func (x *AuthorizationRule) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+// FieldBinding fills a request field from the request headers instead of the body or the query.
+type FieldBinding struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// HTTP header (or gRPC metadata key) copied into the field, case-insensitive.
+	// The header overrides a value sent in the body or the query.
+	Header string `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// Reject calls without the header with InvalidArgument
+	Required      bool `protobuf:"varint,2,opt,name=required,proto3" json:"required,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldBinding) Reset() {
+	*x = FieldBinding{}
+	mi := &file_pb_options_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldBinding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldBinding) ProtoMessage() {}
+
+func (x *FieldBinding) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_options_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldBinding.ProtoReflect.Descriptor instead.
+func (*FieldBinding) Descriptor() ([]byte, []int) {
+	return file_pb_options_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FieldBinding) GetHeader() string {
+	if x != nil {
+		return x.Header
+	}
+	return ""
+}
+
+func (x *FieldBinding) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
+var file_pb_options_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*AuthorizationRule)(nil),
+		Field:         50100,
+		Name:          "discoverservicepb.authorization",
+		Tag:           "bytes,50100,opt,name=authorization",
+		Filename:      "pb/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*FieldBinding)(nil),
+		Field:         50101,
+		Name:          "discoverservicepb.bind",
+		Tag:           "bytes,50101,opt,name=bind",
+		Filename:      "pb/options.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// Access rule enforced before the handler runs.
+	// Methods without a rule are open to every authenticated caller.
+	//
+	// optional discoverservicepb.AuthorizationRule authorization = 50100;
+	E_Authorization = &file_pb_options_proto_extTypes[0]
+)
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// Binding applied to the request before the handler runs.
+	// Only top-level scalar fields of request messages can be bound.
+	//
+	// optional discoverservicepb.FieldBinding bind = 50101;
+	E_Bind = &file_pb_options_proto_extTypes[1]
+)
+
+var File_pb_options_proto protoreflect.FileDescriptor
+
+const file_pb_options_proto_rawDesc = "" +
+	"\n" +
+	"\x10pb/options.proto\x12\x11discoverservicepb\x1a google/protobuf/descriptor.proto\"A\n" +
+	"\x11AuthorizationRule\x12\x14\n" +
+	"\x05roles\x18\x01 \x03(\tR\x05roles\x12\x16\n" +
+	"\x06scopes\x18\x02 \x03(\tR\x06scopes\"B\n" +
+	"\fFieldBinding\x12\x16\n" +
+	"\x06header\x18\x01 \x01(\tR\x06header\x12\x1a\n" +
+	"\brequired\x18\x02 \x01(\bR\brequired:l\n" +
+	"\rauthorization\x12\x1e.google.protobuf.MethodOptions\x18\xb4\x87\x03 \x01(\v2$.discoverservicepb.AuthorizationRuleR\rauthorization:T\n" +
+	"\x04bind\x12\x1d.google.protobuf.FieldOptions\x18\xb5\x87\x03 \x01(\v2\x1f.discoverservicepb.FieldBindingR\x04bindB\x14Z\x12/discoverservicepbb\x06proto3"
+
+var (
+	file_pb_options_proto_rawDescOnce sync.Once
+	file_pb_options_proto_rawDescData []byte
+)
+
+func file_pb_options_proto_rawDescGZIP() []byte {
+	file_pb_options_proto_rawDescOnce.Do(func() {
+		file_pb_options_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pb_options_proto_rawDesc), len(file_pb_options_proto_rawDesc)))
+	})
+	return file_pb_options_proto_rawDescData
+}
+
+var file_pb_options_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_pb_options_proto_goTypes = []any{
+	(*AuthorizationRule)(nil),          // 0: discoverservicepb.AuthorizationRule
+	(*FieldBinding)(nil),               // 1: discoverservicepb.FieldBinding
+	(*descriptorpb.MethodOptions)(nil), // 2: google.protobuf.MethodOptions
+	(*descriptorpb.FieldOptions)(nil),  // 3: google.protobuf.FieldOptions
+}
+var file_pb_options_proto_depIdxs = []int32{
+	2, // 0: discoverservicepb.authorization:extendee -> google.protobuf.MethodOptions
+	3, // 1: discoverservicepb.bind:extendee -> google.protobuf.FieldOptions
+	0, // 2: discoverservicepb.authorization:type_name -> discoverservicepb.AuthorizationRule
+	1, // 3: discoverservicepb.bind:type_name -> discoverservicepb.FieldBinding
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	2, // [2:4] is the sub-list for extension type_name
+	0, // [0:2] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_pb_options_proto_init() }
+func file_pb_options_proto_init() {
+	if File_pb_options_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pb_options_proto_rawDesc), len(file_pb_options_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 2,
+			NumServices:   0,
+		},
+		GoTypes:           file_pb_options_proto_goTypes,
+		DependencyIndexes: file_pb_options_proto_depIdxs,
+		MessageInfos:      file_pb_options_proto_msgTypes,
+		ExtensionInfos:    file_pb_options_proto_extTypes,
+	}.Build()
+	File_pb_options_proto = out.File
+	file_pb_options_proto_goTypes = nil
+	file_pb_options_proto_depIdxs = nil
+}
@@ -1,3 +1,8 @@
+protoc -I . \
+  -I third_party \
+  --go_out . --go_opt paths=source_relative \
+  ./pb/options.proto
+
 protoc -I . \
   -I third_party \
   --go_out . --go_opt paths=source_relative \
@@ -5,4 +10,4 @@ protoc -I . \
   --grpc-gateway_out . --grpc-gateway_opt paths=source_relative \
   --openapiv2_out . \
   --openapiv2_opt logtostderr=true \
-  ./pb/discover.proto
\ No newline at end of file
+  ./pb/discover.proto
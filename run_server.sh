@@ -6,10 +6,11 @@ echo "========================================"
 # Check if the protobuf files exist
 if [ ! -f "pb/discover.pb.go" ]; then
     echo "Error: Protobuf files not found. Please run the protoc command first:"
-    echo "protoc -I. -I./third_party -I./pb --go_out=. --go-grpc_out=. --grpc-gateway_out=. pb/discover.proto"
+    echo "sh pb/run.sh"
     exit 1
 fi
 
 # Run the server
 echo "Starting server..."
-go run server/*go 
\ No newline at end of file
+# The demo runs without credentials; configure JWT keys or -api-keys-file for anything else
+go run server/*go -insecure-disable-auth
\ No newline at end of file
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// AccessLogFormat selects how access log entries are written
+type AccessLogFormat string
+
+const (
+	// AccessLogOff writes no access log
+	AccessLogOff AccessLogFormat = "off"
+	// AccessLogCommon writes the Common Log Format of the NCSA and Apache servers
+	AccessLogCommon AccessLogFormat = "common"
+	// AccessLogCombined writes the Common Log Format followed by the referer and user agent
+	AccessLogCombined AccessLogFormat = "combined"
+	// AccessLogJSON writes one JSON object per request
+	AccessLogJSON AccessLogFormat = "json"
+	// AccessLogTemplate writes the access log template executed with each AccessLogEntry
+	AccessLogTemplate AccessLogFormat = "template"
+)
+
+// defaultAccessLogTemplate is the access log template unless configured otherwise
+const defaultAccessLogTemplate = `{{.Time.Format "2006-01-02T15:04:05.000Z07:00"}} {{.ClientIP}} {{.Method}} {{.Path}} route={{.Route}} status={{.Status}} bytes={{.Bytes}} duration={{.Duration}} request_id={{.RequestID}} user_agent={{printf "%q" .UserAgent}}`
+
+// clfTimeFormat is the timestamp layout of the Common Log Format
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// parseAccessLogFormat parses an access log format name
+func parseAccessLogFormat(name string) (AccessLogFormat, error) {
+	switch format := AccessLogFormat(strings.ToLower(name)); format {
+	case AccessLogOff, AccessLogCommon, AccessLogCombined, AccessLogJSON, AccessLogTemplate:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown access log format %q (want %q, %q, %q, %q or %q)", name, AccessLogOff, AccessLogCommon, AccessLogCombined, AccessLogJSON, AccessLogTemplate)
+	}
+}
+
+// parseAccessLogTemplate parses the text/template of the template format. The template is
+// executed once so that unknown fields are reported on startup rather than for every request.
+func parseAccessLogTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("access_log").Parse(text)
+	if err == nil {
+		err = tmpl.Execute(io.Discard, &AccessLogEntry{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid access log template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// AccessLogEntry describes a request of the access log. The template format executes the
+// access log template with it, e.g. {{.ClientIP}} {{.Method}} {{.Route}} {{.Status}}.
+type AccessLogEntry struct {
+	// Time is when the request was received
+	Time time.Time `json:"time"`
+	// RequestID is the ID given by RequestIDMiddleware
+	RequestID string `json:"request_id,omitempty"`
+	// ClientIP is the address of the client, as resolved by ClientIPMiddleware
+	ClientIP string `json:"client_ip"`
+	Method   string `json:"method"`
+	// Path is the request path; the query string is left out as it may carry credentials
+	Path string `json:"path"`
+	// Route is the route pattern the gateway matched, empty when none matched
+	Route    string `json:"route,omitempty"`
+	Protocol string `json:"protocol"`
+	// Status is the status code captured by ErrorResponseWriter
+	Status int `json:"status"`
+	// Bytes is the size of the response body
+	Bytes      int64         `json:"bytes"`
+	Duration   time.Duration `json:"-"`
+	DurationMS float64       `json:"duration_ms"`
+	UserAgent  string        `json:"user_agent"`
+	Referer    string        `json:"referer,omitempty"`
+}
+
+// AccessLogger writes an access log entry for each HTTP request
+type AccessLogger struct {
+	mu       sync.Mutex
+	w        io.Writer
+	format   AccessLogFormat
+	template *template.Template
+}
+
+// NewAccessLogger creates an access logger writing entries in format to w.
+// tmpl is the template of the template format.
+func NewAccessLogger(w io.Writer, format AccessLogFormat, tmpl string) (*AccessLogger, error) {
+	l := &AccessLogger{w: w, format: format}
+	if format == AccessLogTemplate {
+		var err error
+		if l.template, err = parseAccessLogTemplate(tmpl); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// openAccessLog creates the access logger configured by cfg, writing to stdout or appending
+// to the access log file. The returned function closes the file.
+func openAccessLog(cfg *Config) (*AccessLogger, func() error, error) {
+	var w io.Writer = os.Stdout
+	closeFile := func() error { return nil }
+	if cfg.AccessLogFormat != AccessLogOff && cfg.AccessLogFile != "" {
+		file, err := os.OpenFile(cfg.AccessLogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open access log file: %w", err)
+		}
+		w, closeFile = file, file.Close
+	}
+
+	l, err := NewAccessLogger(w, cfg.AccessLogFormat, cfg.AccessLogTemplate)
+	if err != nil {
+		closeFile()
+		return nil, nil, err
+	}
+	return l, closeFile, nil
+}
+
+// Middleware writes an entry for each request once it is handled. It must wrap
+// ErrorHandlingMiddleware, whose ErrorResponseWriter captures the status and size of the
+// response, and run inside the middleware giving the request its ID, client IP and route.
+func (l *AccessLogger) Middleware(next http.Handler) http.Handler {
+	if l.format == AccessLogOff {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, route := withRequestRoute(r.Context())
+		errorWriter := &ErrorResponseWriter{ResponseWriter: w, Request: r}
+
+		next.ServeHTTP(errorWriter, r.WithContext(ctx))
+
+		duration := time.Since(start)
+		l.write(&AccessLogEntry{
+			Time:       start,
+			RequestID:  requestIDFromContext(ctx),
+			ClientIP:   clientIPOf(r),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Route:      route.pattern,
+			Protocol:   r.Proto,
+			Status:     errorWriter.StatusCode(),
+			Bytes:      errorWriter.BytesWritten(),
+			Duration:   duration,
+			DurationMS: float64(duration.Microseconds()) / 1000,
+			UserAgent:  r.UserAgent(),
+			Referer:    r.Referer(),
+		})
+	})
+}
+
+// clientIPOf returns the client IP resolved by ClientIPMiddleware, or the peer address without it
+func clientIPOf(r *http.Request) string {
+	if ip, ok := clientIPFromContext(r.Context()); ok {
+		return ip
+	}
+	return TrustedProxies(nil).clientIP(r.RemoteAddr, nil)
+}
+
+// write formats the entry and writes it as one line
+func (l *AccessLogger) write(entry *AccessLogEntry) {
+	line, err := l.formatEntry(entry)
+	if err != nil {
+		slog.Error("Failed to format access log entry", "format", l.format, "error", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(line); err != nil {
+		slog.Error("Failed to write access log entry", "error", err)
+	}
+}
+
+// formatEntry renders the entry in the format of the logger, ending with a newline
+func (l *AccessLogger) formatEntry(entry *AccessLogEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	switch l.format {
+	case AccessLogCommon, AccessLogCombined:
+		// host ident authuser [date] "request line" status bytes
+		size := "-"
+		if entry.Bytes > 0 {
+			size = strconv.FormatInt(entry.Bytes, 10)
+		}
+		fmt.Fprintf(&buf, "%s - - [%s] %s %d %s",
+			entry.ClientIP,
+			entry.Time.Format(clfTimeFormat),
+			strconv.Quote(entry.Method+" "+entry.Path+" "+entry.Protocol),
+			entry.Status,
+			size,
+		)
+		if l.format == AccessLogCombined {
+			fmt.Fprintf(&buf, " %s %s", clfField(entry.Referer), clfField(entry.UserAgent))
+		}
+	case AccessLogJSON:
+		if err := json.NewEncoder(&buf).Encode(entry); err != nil {
+			return nil, err
+		}
+	case AccessLogTemplate:
+		if err := l.template.Execute(&buf, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// clfField quotes a header value of the combined format, "-" when it is empty
+func clfField(value string) string {
+	if value == "" {
+		return `"-"`
+	}
+	return strconv.Quote(value)
+}
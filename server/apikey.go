@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// apiKeyMetadataKey is the metadata key the gateway forwards the X-API-Key header as
+	apiKeyMetadataKey = "x-api-key"
+	// apiKeyPrefix starts every API key, followed by the key id and the secret
+	apiKeyPrefix = "dsk_"
+	// apiKeyHashPrefix names the hash function of APIKeyRecord.Hash
+	apiKeyHashPrefix = "sha256:"
+)
+
+// APIKeyRecord is an issued API key. Only the hash of the key is stored.
+type APIKeyRecord struct {
+	ID         string     `json:"id"`
+	Hash       string     `json:"hash"`
+	Owner      string     `json:"owner"`
+	Tenant     string     `json:"tenant,omitempty"`
+	Scopes     []string   `json:"scopes,omitempty"`
+	CreateTime time.Time  `json:"create_time"`
+	ExpireTime *time.Time `json:"expire_time,omitempty"`
+	RevokeTime *time.Time `json:"revoke_time,omitempty"`
+}
+
+// active reports whether the key can be used at the given time
+func (r *APIKeyRecord) active(now time.Time) bool {
+	return r.RevokeTime == nil && (r.ExpireTime == nil || now.Before(*r.ExpireTime))
+}
+
+// apiKeyFile is the on-disk format of the API key store
+type apiKeyFile struct {
+	Keys []*APIKeyRecord `json:"keys"`
+}
+
+// APIKeyStore verifies API keys against the hashes kept in a JSON file
+type APIKeyStore struct {
+	path string
+
+	mu   sync.RWMutex
+	keys map[string]*APIKeyRecord
+}
+
+// OpenAPIKeyStore loads the API key file at path
+func OpenAPIKeyStore(path string) (*APIKeyStore, error) {
+	s := &APIKeyStore{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the key file again, picking up keys created, rotated or revoked since
+func (s *APIKeyStore) Reload() error {
+	file, err := readAPIKeyFile(s.path)
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]*APIKeyRecord, len(file.Keys))
+	for _, record := range file.Keys {
+		keys[record.ID] = record
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+// Verify returns the record of an active API key
+func (s *APIKeyStore) Verify(key string) (*APIKeyRecord, error) {
+	id, ok := apiKeyID(key)
+	if !ok {
+		return nil, unauthenticatedError("INVALID_API_KEY", "invalid API key")
+	}
+
+	s.mu.RLock()
+	record, ok := s.keys[id]
+	s.mu.RUnlock()
+	if !ok || subtle.ConstantTimeCompare([]byte(record.Hash), []byte(hashAPIKey(key))) != 1 || record.RevokeTime != nil {
+		return nil, unauthenticatedError("INVALID_API_KEY", "invalid API key")
+	}
+	if !record.active(time.Now()) {
+		return nil, unauthenticatedError("API_KEY_EXPIRED", "API key is expired")
+	}
+	return record, nil
+}
+
+// authenticate verifies the X-API-Key of the request
+func (s *APIKeyStore) authenticate(ctx context.Context) (*APIKeyRecord, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(apiKeyMetadataKey)
+	if len(values) == 0 {
+		return nil, unauthenticatedError("MISSING_CREDENTIALS", "X-API-Key header is required")
+	}
+	return s.Verify(values[0])
+}
+
+// apiKeyID extracts the key id from a key of the form dsk_<id>_<secret>
+func apiKeyID(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// hashAPIKey returns the stored form of a key
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return apiKeyHashPrefix + hex.EncodeToString(sum[:])
+}
+
+// generateAPIKey returns a new random key together with its id
+func generateAPIKey() (key, id string, err error) {
+	idBytes := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(idBytes)
+	return apiKeyPrefix + id + "_" + base64.RawURLEncoding.EncodeToString(secret), id, nil
+}
+
+// readAPIKeyFile reads the key file; a missing file holds no keys
+func readAPIKeyFile(path string) (*apiKeyFile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &apiKeyFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read API key file: %w", err)
+	}
+	file := &apiKeyFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("parse API key file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// writeAPIKeyFile replaces the key file atomically, readable by the owner only
+func writeAPIKeyFile(path string, file *apiKeyFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
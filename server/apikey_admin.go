@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// defaultAPIKeyFile is the key file used by the apikey command when -file is not given
+const defaultAPIKeyFile = "data/api_keys.json"
+
+// runAPIKeyCommand implements "server apikey create|rotate|revoke|list".
+// It edits the key file; running servers pick up the changes on reload.
+func runAPIKeyCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: server apikey create|rotate|revoke|list [flags]")
+	}
+
+	command, args := args[0], args[1:]
+	fs := flag.NewFlagSet("apikey "+command, flag.ContinueOnError)
+	path := fs.String("file", defaultAPIKeyFile, "API key file")
+
+	switch command {
+	case "create":
+		owner := fs.String("owner", "", "owner of the key, e.g. the partner name (required)")
+		tenant := fs.String("tenant", "", "tenant the key is bound to; keys without a tenant belong to the \""+defaultTenant+"\" tenant, or are rejected with -require-tenant")
+		scopes := fs.String("scopes", "", "comma separated scopes granted to the key")
+		ttl := fs.Duration("ttl", 0, "lifetime of the key; 0 never expires")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *owner == "" {
+			return errors.New("-owner is required")
+		}
+		if *tenant != "" && !tenantIDPattern.MatchString(*tenant) {
+			return fmt.Errorf("invalid tenant id %q", *tenant)
+		}
+		return createAPIKey(*path, *owner, *tenant, splitScopes(*scopes), *ttl)
+	case "rotate":
+		id := fs.String("id", "", "id of the key to rotate (required)")
+		grace := fs.Duration("grace", 24*time.Hour, "how long the old key keeps working")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *id == "" {
+			return errors.New("-id is required")
+		}
+		return rotateAPIKey(*path, *id, *grace)
+	case "revoke":
+		id := fs.String("id", "", "id of the key to revoke (required)")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *id == "" {
+			return errors.New("-id is required")
+		}
+		return revokeAPIKey(*path, *id)
+	case "list":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		return listAPIKeys(*path)
+	default:
+		return fmt.Errorf("unknown apikey command %q (want create, rotate, revoke or list)", command)
+	}
+}
+
+// createAPIKey issues a key and prints it; the key itself is not stored and cannot be shown again
+func createAPIKey(path, owner, tenant string, scopes []string, ttl time.Duration) error {
+	file, err := readAPIKeyFile(path)
+	if err != nil {
+		return err
+	}
+
+	key, record, err := newAPIKeyRecord(owner, tenant, scopes, ttl)
+	if err != nil {
+		return err
+	}
+	file.Keys = append(file.Keys, record)
+	if err := writeAPIKeyFile(path, file); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created API key %s for %s\n", record.ID, owner)
+	fmt.Printf("Key: %s\n", key)
+	fmt.Println("Store the key now; it cannot be shown again.")
+	return nil
+}
+
+// rotateAPIKey issues a replacement key with the same owner, tenant, scopes and lifetime,
+// and expires the old key after the grace period
+func rotateAPIKey(path, id string, grace time.Duration) error {
+	file, err := readAPIKeyFile(path)
+	if err != nil {
+		return err
+	}
+	old := findAPIKey(file, id)
+	if old == nil {
+		return fmt.Errorf("API key %s not found", id)
+	}
+	if old.RevokeTime != nil {
+		return fmt.Errorf("API key %s is revoked", id)
+	}
+
+	var ttl time.Duration
+	if old.ExpireTime != nil {
+		ttl = old.ExpireTime.Sub(old.CreateTime)
+	}
+	key, record, err := newAPIKeyRecord(old.Owner, old.Tenant, old.Scopes, ttl)
+	if err != nil {
+		return err
+	}
+
+	oldExpiry := time.Now().UTC().Add(grace)
+	if old.ExpireTime == nil || oldExpiry.Before(*old.ExpireTime) {
+		old.ExpireTime = &oldExpiry
+	}
+	file.Keys = append(file.Keys, record)
+	if err := writeAPIKeyFile(path, file); err != nil {
+		return err
+	}
+
+	fmt.Printf("Rotated API key %s to %s for %s\n", id, record.ID, record.Owner)
+	fmt.Printf("Key: %s\n", key)
+	fmt.Printf("The old key expires at %s.\n", old.ExpireTime.Format(time.RFC3339))
+	return nil
+}
+
+// revokeAPIKey disables a key immediately
+func revokeAPIKey(path, id string) error {
+	file, err := readAPIKeyFile(path)
+	if err != nil {
+		return err
+	}
+	record := findAPIKey(file, id)
+	if record == nil {
+		return fmt.Errorf("API key %s not found", id)
+	}
+	if record.RevokeTime == nil {
+		now := time.Now().UTC()
+		record.RevokeTime = &now
+	}
+	if err := writeAPIKeyFile(path, file); err != nil {
+		return err
+	}
+
+	fmt.Printf("Revoked API key %s of %s\n", id, record.Owner)
+	return nil
+}
+
+// listAPIKeys prints the keys without their hashes
+func listAPIKeys(path string) error {
+	file, err := readAPIKeyFile(path)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tOWNER\tTENANT\tSCOPES\tCREATED\tEXPIRES\tSTATE")
+	for _, record := range file.Keys {
+		expires := "never"
+		if record.ExpireTime != nil {
+			expires = record.ExpireTime.Format(time.RFC3339)
+		}
+		state := "active"
+		switch {
+		case record.RevokeTime != nil:
+			state = "revoked"
+		case !record.active(now):
+			state = "expired"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", record.ID, record.Owner, record.Tenant, strings.Join(record.Scopes, ","),
+			record.CreateTime.Format(time.RFC3339), expires, state)
+	}
+	return w.Flush()
+}
+
+// newAPIKeyRecord generates a key and the record storing its hash
+func newAPIKeyRecord(owner, tenant string, scopes []string, ttl time.Duration) (string, *APIKeyRecord, error) {
+	key, id, err := generateAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now().UTC()
+	record := &APIKeyRecord{
+		ID:         id,
+		Hash:       hashAPIKey(key),
+		Owner:      owner,
+		Tenant:     tenant,
+		Scopes:     scopes,
+		CreateTime: now,
+	}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		record.ExpireTime = &expires
+	}
+	return key, record, nil
+}
+
+// findAPIKey returns the record with the given id, or nil
+func findAPIKey(file *apiKeyFile, id string) *APIKeyRecord {
+	for _, record := range file.Keys {
+		if record.ID == id {
+			return record
+		}
+	}
+	return nil
+}
+
+// splitScopes parses a comma separated scope list
+func splitScopes(value string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(value, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
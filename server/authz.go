@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	discoverservicepb "protobuf-http-golang/pb"
+)
+
+// AuthorizationPolicy holds the (authorization) rules of the RPCs, keyed by full method name
+type AuthorizationPolicy map[string]*discoverservicepb.AuthorizationRule
+
+// loadAuthorizationPolicy reads the (authorization) method options of the services
+func loadAuthorizationPolicy(services ...protoreflect.ServiceDescriptor) AuthorizationPolicy {
+	policy := AuthorizationPolicy{}
+	for _, service := range services {
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			rule, _ := proto.GetExtension(method.Options(), discoverservicepb.E_Authorization).(*discoverservicepb.AuthorizationRule)
+			if rule == nil {
+				continue
+			}
+			policy["/"+string(service.FullName())+"/"+string(method.Name())] = rule
+		}
+	}
+	return policy
+}
+
+// authorize checks the claims of the caller against the rule of the method.
+// The caller needs one of the rule's roles or all of its scopes.
+func (p AuthorizationPolicy) authorize(fullMethod string, claims jwt.MapClaims) error {
+	rule, ok := p[fullMethod]
+	if !ok {
+		return nil
+	}
+	roles, scopes := rule.GetRoles(), rule.GetScopes()
+	if len(roles) == 0 && len(scopes) == 0 {
+		return nil
+	}
+
+	grantedRoles := claimValues(claims, "roles", false)
+	if slices.ContainsFunc(roles, func(role string) bool { return slices.Contains(grantedRoles, role) }) {
+		return nil
+	}
+
+	grantedScopes := append(claimValues(claims, "scope", true), claimValues(claims, "scp", true)...)
+	var missing []string
+	for _, scope := range scopes {
+		if !slices.Contains(grantedScopes, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(scopes) > 0 && len(missing) == 0 {
+		return nil
+	}
+
+	metadata := map[string]string{"method": fullMethod}
+	var requirements []string
+	reason := "MISSING_ROLE"
+	if len(roles) > 0 {
+		metadata["required_roles"] = strings.Join(roles, " ")
+		requirements = append(requirements, "one of the roles "+strings.Join(roles, ", "))
+	}
+	if len(scopes) > 0 {
+		metadata["missing_scopes"] = strings.Join(missing, " ")
+		requirements = append(requirements, "the scopes "+strings.Join(scopes, ", "))
+		reason = "MISSING_SCOPE"
+		if len(roles) > 0 {
+			reason = "MISSING_ROLE_OR_SCOPE"
+		}
+	}
+	return permissionDeniedError(reason, fullMethod+" requires "+strings.Join(requirements, " or "), metadata)
+}
+
+// claimValues reads a claim holding either a list of strings or a single string.
+// With split, a single string is split on spaces, as in the OAuth 2.0 "scope" claim.
+func claimValues(claims jwt.MapClaims, name string, split bool) []string {
+	switch value := claims[name].(type) {
+	case string:
+		if split {
+			return strings.Fields(value)
+		}
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// UnaryAuthorizationInterceptor enforces the authorization policy with PermissionDenied.
+// It must run after UnaryAuthInterceptor, which provides the caller claims.
+func UnaryAuthorizationInterceptor(policy AuthorizationPolicy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		claims, _ := ClaimsFromContext(ctx)
+		if err := policy.authorize(info.FullMethod, claims); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	discoverservicepb "protobuf-http-golang/pb"
+)
+
+// headerBinding is a request field filled from a header with the (bind) option
+type headerBinding struct {
+	field    protoreflect.FieldDescriptor
+	header   string
+	required bool
+}
+
+// HeaderBindings holds the (bind) header bindings of the request fields, keyed by full method name
+type HeaderBindings map[string][]headerBinding
+
+// loadHeaderBindings reads the (bind) field options of the request messages of the services
+func loadHeaderBindings(services ...protoreflect.ServiceDescriptor) HeaderBindings {
+	bindings := HeaderBindings{}
+	for _, service := range services {
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			fields := method.Input().Fields()
+			for j := 0; j < fields.Len(); j++ {
+				field := fields.Get(j)
+				binding, _ := proto.GetExtension(field.Options(), discoverservicepb.E_Bind).(*discoverservicepb.FieldBinding)
+				if binding.GetHeader() == "" {
+					continue
+				}
+				if field.Kind() == protoreflect.MessageKind || field.Kind() == protoreflect.GroupKind {
+					slog.Warn("Ignoring (bind) option: only scalar fields can be bound", "field", field.FullName())
+					continue
+				}
+				fullMethod := "/" + string(service.FullName()) + "/" + string(method.Name())
+				bindings[fullMethod] = append(bindings[fullMethod], headerBinding{
+					field:    field,
+					header:   binding.GetHeader(),
+					required: binding.GetRequired(),
+				})
+			}
+		}
+	}
+	return bindings
+}
+
+// headers returns the names of the bound headers, which the gateway must forward as metadata
+func (b HeaderBindings) headers() []string {
+	var headers []string
+	for _, bindings := range b {
+		for _, binding := range bindings {
+			headers = append(headers, strings.ToLower(binding.header))
+		}
+	}
+	return headers
+}
+
+// bind copies the bound headers of the call into the request
+func (b HeaderBindings) bind(ctx context.Context, fullMethod string, req proto.Message) error {
+	bindings := b[fullMethod]
+	if len(bindings) == 0 {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	message := req.ProtoReflect()
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, binding := range bindings {
+		values := md.Get(binding.header)
+		if len(values) == 0 {
+			if binding.required {
+				violations = append(violations, fieldViolation(string(binding.field.Name()), binding.header+" header is required"))
+			}
+			continue
+		}
+		if err := setBoundField(message, binding.field, values); err != nil {
+			violations = append(violations, fieldViolation(string(binding.field.Name()), binding.header+" header "+err.Error()))
+		}
+	}
+	if len(violations) > 0 {
+		return invalidArgumentError(violations...)
+	}
+	return nil
+}
+
+// setBoundField stores header values in a scalar field; repeated fields take every value
+func setBoundField(message protoreflect.Message, field protoreflect.FieldDescriptor, values []string) error {
+	if field.IsList() {
+		list := message.Mutable(field).List()
+		list.Truncate(0)
+		for _, value := range values {
+			v, err := parseBoundValue(field, value)
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+		return nil
+	}
+
+	v, err := parseBoundValue(field, values[0])
+	if err != nil {
+		return err
+	}
+	message.Set(field, v)
+	return nil
+}
+
+// parseBoundValue converts a header value to the kind of the field
+func parseBoundValue(field protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(value)), nil
+	case protoreflect.BoolKind:
+		if v, err := strconv.ParseBool(value); err == nil {
+			return protoreflect.ValueOfBool(v), nil
+		}
+		return protoreflect.Value{}, errors.New("must be true or false")
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if v, err := strconv.ParseInt(value, 10, 32); err == nil {
+			return protoreflect.ValueOfInt32(int32(v)), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return protoreflect.ValueOfInt64(v), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if v, err := strconv.ParseUint(value, 10, 32); err == nil {
+			return protoreflect.ValueOfUint32(uint32(v)), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if v, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return protoreflect.ValueOfUint64(v), nil
+		}
+	case protoreflect.FloatKind:
+		if v, err := strconv.ParseFloat(value, 32); err == nil {
+			return protoreflect.ValueOfFloat32(float32(v)), nil
+		}
+	case protoreflect.DoubleKind:
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return protoreflect.ValueOfFloat64(v), nil
+		}
+	case protoreflect.EnumKind:
+		if v := field.Enum().Values().ByName(protoreflect.Name(value)); v != nil {
+			return protoreflect.ValueOfEnum(v.Number()), nil
+		}
+		return protoreflect.Value{}, errors.New("must be one of the " + string(field.Enum().Name()) + " values")
+	}
+	return protoreflect.Value{}, errors.New("must be a " + field.Kind().String() + " number")
+}
+
+// UnaryHeaderBindingInterceptor fills the (bind) fields of requests from their headers and
+// rejects calls missing a required header with InvalidArgument
+func UnaryHeaderBindingInterceptor(bindings HeaderBindings) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if message, ok := req.(proto.Message); ok {
+			if err := bindings.bind(ctx, info.FullMethod, message); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// forwardedForMetadataKey is the metadata key of the X-Forwarded-For header
+	forwardedForMetadataKey = "x-forwarded-for"
+	// gatewayClientIPMetadataKey carries the client IP resolved by ClientIPMiddleware from the
+	// gateway to the service. It is believed from loopback peers only, as the gateway of endpoint
+	// mode dials the gRPC listener on loopback.
+	gatewayClientIPMetadataKey = "x-gateway-client-ip"
+)
+
+// TrustedProxies are the addresses of the proxies whose X-Forwarded-For header is believed
+type TrustedProxies []netip.Prefix
+
+// parseTrustedProxies parses IP addresses and CIDR ranges such as 10.0.0.0/8
+func parseTrustedProxies(entries []string) (TrustedProxies, error) {
+	prefixes := make(TrustedProxies, 0, len(entries))
+	for _, entry := range entries {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: want an IP address or CIDR range", entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// contains reports whether ip belongs to a trusted proxy
+func (p TrustedProxies) contains(ip netip.Addr) bool {
+	for _, prefix := range p {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client of a connection from peer, a host or host:port,
+// carrying the X-Forwarded-For values forwarded. The header is read from the nearest proxy
+// backwards while the addresses belong to trusted proxies; the first address that does not is
+// the client. Without trusted proxies the header is ignored, so clients cannot forge their address.
+func (p TrustedProxies) clientIP(peer string, forwarded []string) string {
+	host, _, err := net.SplitHostPort(peer)
+	if err != nil {
+		host = peer
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	ip = ip.Unmap()
+
+	entries := strings.Split(strings.Join(forwarded, ","), ",")
+	for i := len(entries) - 1; i >= 0 && p.contains(ip); i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(entries[i]))
+		if err != nil {
+			break
+		}
+		ip = addr.Unmap()
+	}
+	return ip.String()
+}
+
+// clientIPKey is the context key of the client IP of an HTTP request
+type clientIPKey struct{}
+
+// clientIPFromContext returns the client IP resolved by ClientIPMiddleware
+func clientIPFromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPKey{}).(string)
+	return ip, ok
+}
+
+// isLoopback reports whether peer, a host or host:port, is a loopback address
+func isLoopback(peer string) bool {
+	host, _, err := net.SplitHostPort(peer)
+	if err != nil {
+		host = peer
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.Unmap().IsLoopback()
+}
+
+// GatewayClientIPMetadata forwards the client IP resolved by ClientIPMiddleware to the service,
+// so that the calls of the gateway in endpoint mode are not all counted as 127.0.0.1.
+// Register it with runtime.WithMetadata.
+func GatewayClientIPMetadata(_ context.Context, r *http.Request) metadata.MD {
+	if ip, ok := clientIPFromContext(r.Context()); ok {
+		return metadata.Pairs(gatewayClientIPMetadataKey, ip)
+	}
+	return nil
+}
+
+// ClientIPMiddleware resolves the client IP of each HTTP request, believing the X-Forwarded-For
+// header of trusted proxies only, for the access log and the calls of the in-process gateway
+func ClientIPMiddleware(proxies TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := proxies.clientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"))
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	}
+}
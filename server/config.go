@@ -0,0 +1,556 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GatewayMode selects how the HTTP gateway reaches the service implementation
+type GatewayMode string
+
+const (
+	// GatewayModeInProcess calls the service implementation directly
+	GatewayModeInProcess GatewayMode = "inprocess"
+	// GatewayModeEndpoint dials the gRPC listener, like a gateway deployed in front of a gRPC service
+	GatewayModeEndpoint GatewayMode = "endpoint"
+)
+
+const (
+	// configFileEnv names the configuration file when -config is not given
+	configFileEnv = "DISCOVER_CONFIG"
+	// configEnvPrefix starts the environment variables overriding settings, e.g. DISCOVER_HTTP_ADDR for -http-addr
+	configEnvPrefix = "DISCOVER_"
+)
+
+// Config holds the server settings.
+// Settings are read from the defaults, a YAML or JSON file, DISCOVER_* environment
+// variables and command line flags, each overriding the previous ones.
+type Config struct {
+	// ConfigFile is the file the settings were read from, if any
+	ConfigFile string `yaml:"-"`
+	// HTTPAddr is the address of the HTTP gateway (and of everything in single-port mode)
+	HTTPAddr string `yaml:"http_addr"`
+	// GRPCAddr is the address of the native gRPC listener
+	GRPCAddr string `yaml:"grpc_addr"`
+	// SwaggerAddr is the address of the Swagger UI when it does not share the HTTP port
+	SwaggerAddr string `yaml:"swagger_addr"`
+	// SwaggerFile is the OpenAPI document served by the Swagger UI
+	SwaggerFile string `yaml:"swagger_file"`
+	// MetricsAddr is the admin address serving the Prometheus metrics on /metrics; disabled when empty
+	MetricsAddr string `yaml:"metrics_addr"`
+	// SinglePort serves gRPC, the REST gateway and the Swagger UI on HTTPAddr
+	SinglePort bool `yaml:"single_port"`
+	// GatewayMode selects how the gateway calls the service
+	GatewayMode GatewayMode `yaml:"gateway_mode"`
+	// ShutdownTimeout bounds the graceful shutdown of the servers
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// ErrorFormat is the default error response format
+	ErrorFormat ErrorFormat `yaml:"error_format"`
+	// LogLevel is the minimum level of the log records written
+	LogLevel slog.Level `yaml:"log_level"`
+	// LogFormat is the format of the log records
+	LogFormat LogFormat `yaml:"log_format"`
+	// LogRedact are the log attribute keys whose values are replaced by [REDACTED]
+	LogRedact []string `yaml:"log_redact"`
+	// AccessLogFormat is the format of the access log of the HTTP gateway
+	AccessLogFormat AccessLogFormat `yaml:"access_log_format"`
+	// AccessLogTemplate is the text/template of the template access log format, executed with an AccessLogEntry
+	AccessLogTemplate string `yaml:"access_log_template"`
+	// AccessLogFile is the file the access log is appended to; stdout when empty
+	AccessLogFile string `yaml:"access_log_file"`
+	// TrustedProxies are the IP addresses and CIDR ranges of the proxies whose X-Forwarded-For is believed
+	// for the client IP of the access log and of the per-IP rate limit. The gateway's own hop in
+	// endpoint mode is trusted without being listed.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// LogErrors logs the errors returned to clients
+	LogErrors bool `yaml:"log_errors"`
+	// ExposePanicDetails includes the panic message and stack trace in error responses
+	ExposePanicDetails bool `yaml:"expose_panic_details"`
+	// WatchConfig reloads the configuration and API key files when they change
+	WatchConfig bool `yaml:"watch_config"`
+	// TraceExporter selects where spans are exported
+	TraceExporter TraceExporter `yaml:"trace_exporter"`
+	// TraceOTLPEndpoint is the URL of the OTLP/gRPC collector, e.g. http://localhost:4317;
+	// OTEL_EXPORTER_OTLP_ENDPOINT applies when empty
+	TraceOTLPEndpoint string `yaml:"trace_otlp_endpoint"`
+	// TraceFile is the file the file exporter appends spans to
+	TraceFile string `yaml:"trace_file"`
+	// TraceSampleRatio is the fraction of new traces recorded; requests continuing a trace follow its sampling decision
+	TraceSampleRatio float64 `yaml:"trace_sample_ratio"`
+	// StoreKind selects the storage backend for unstructured data
+	StoreKind StoreKind `yaml:"store"`
+	// StorePath is the database file of the bolt store
+	StorePath string `yaml:"store_path"`
+	// PageTokenSecret keys the page tokens of list methods; random when empty
+	PageTokenSecret string `yaml:"page_token_secret"`
+	// IdempotencyWindow is how long responses are kept for replay by Idempotency-Key
+	IdempotencyWindow time.Duration `yaml:"idempotency_window"`
+	// IdempotencyMaxEntries bounds the number of Idempotency-Key responses kept; the oldest are dropped first
+	IdempotencyMaxEntries int `yaml:"idempotency_max_entries"`
+	// IdempotencyMaxBytes bounds the size of the Idempotency-Key responses kept; the oldest are dropped first
+	IdempotencyMaxBytes int64 `yaml:"idempotency_max_bytes"`
+	// JWTHS256Secret is a static HS256 key for bearer tokens
+	JWTHS256Secret string `yaml:"jwt_hs256_secret"`
+	// JWTPublicKeyFile is a PEM encoded RSA (RS256) or EC P-256 (ES256) public key for bearer tokens
+	JWTPublicKeyFile string `yaml:"jwt_public_key"`
+	// JWTJWKSFile is a local JSON Web Key Set with the bearer token keys
+	JWTJWKSFile string `yaml:"jwt_jwks"`
+	// JWTIssuer is the required "iss" claim; not checked when empty
+	JWTIssuer string `yaml:"jwt_issuer"`
+	// JWTAudience is the required "aud" claim; not checked when empty
+	JWTAudience string `yaml:"jwt_audience"`
+	// JWTLeeway is the clock skew allowed when checking "exp" and "nbf"
+	JWTLeeway time.Duration `yaml:"jwt_leeway"`
+	// APIKeysFile is the hashed API key store managed with "server apikey"; API keys are disabled when empty
+	APIKeysFile string `yaml:"api_keys_file"`
+	// InsecureDisableAuth starts the server without JWT keys or API keys, leaving the (authorization)
+	// rules unenforced; without it the server refuses to start unauthenticated
+	InsecureDisableAuth bool `yaml:"insecure_disable_auth"`
+	// TenantClaim is the token claim holding the tenant of authenticated callers
+	TenantClaim string `yaml:"tenant_claim"`
+	// RequireTenant rejects calls that name no tenant, and credentials without the tenant claim,
+	// instead of using the default tenant
+	RequireTenant bool `yaml:"require_tenant"`
+	// RateLimits are the token bucket limits by what they count; a missing key is not limited
+	RateLimits map[RateLimitKey]RateLimit `yaml:"rate_limits"`
+	// RequestHeaders select the HTTP request headers forwarded to the service as gRPC metadata
+	RequestHeaders HeaderRules `yaml:"request_headers"`
+	// ResponseHeaders select the gRPC response metadata written as plain HTTP headers
+	ResponseHeaders HeaderRules `yaml:"response_headers"`
+}
+
+// defaultConfig returns the settings used when nothing overrides them
+func defaultConfig() *Config {
+	return &Config{
+		HTTPAddr:              ":8080",
+		GRPCAddr:              ":9090",
+		SwaggerAddr:           ":8081",
+		SwaggerFile:           "pb/discover.swagger.json",
+		MetricsAddr:           ":9091",
+		GatewayMode:           GatewayModeInProcess,
+		ShutdownTimeout:       30 * time.Second,
+		ErrorFormat:           ErrorFormatJSON,
+		LogLevel:              slog.LevelInfo,
+		LogFormat:             LogFormatJSON,
+		LogRedact:             slices.Clone(defaultLogRedact),
+		AccessLogFormat:       AccessLogCommon,
+		AccessLogTemplate:     defaultAccessLogTemplate,
+		LogErrors:             true,
+		ExposePanicDetails:    true,
+		WatchConfig:           true,
+		TraceExporter:         TraceExporterNone,
+		TraceFile:             "traces.jsonl",
+		TraceSampleRatio:      1,
+		StoreKind:             StoreKindMemory,
+		StorePath:             "data/discover.db",
+		IdempotencyWindow:     24 * time.Hour,
+		IdempotencyMaxEntries: 10000,
+		IdempotencyMaxBytes:   64 << 20,
+		JWTLeeway:             30 * time.Second,
+		TenantClaim:           "tenant",
+		RateLimits:            map[RateLimitKey]RateLimit{},
+	}
+}
+
+// loadConfig builds the Config from the defaults, the configuration file, the environment
+// and the command line flags, in increasing order of precedence, and validates it
+func loadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+
+	path := configFileArg(args)
+	if path == "" {
+		path = os.Getenv(configFileEnv)
+	}
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+		cfg.ConfigFile = path
+		if cfg.RateLimits == nil {
+			// An empty rate_limits section
+			cfg.RateLimits = map[RateLimitKey]RateLimit{}
+		}
+	}
+
+	// Flags default to the values read so far, so that unset flags keep them
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.String("config", path, "YAML or JSON configuration file; also read from $"+configFileEnv+". Every flag can be set with a "+configEnvPrefix+"<FLAG> environment variable, e.g. "+configEnvPrefix+"HTTP_ADDR")
+	fs.StringVar(&cfg.HTTPAddr, "http-addr", cfg.HTTPAddr, "address of the HTTP gateway")
+	fs.StringVar(&cfg.GRPCAddr, "grpc-addr", cfg.GRPCAddr, "address of the native gRPC listener")
+	fs.StringVar(&cfg.SwaggerAddr, "swagger-addr", cfg.SwaggerAddr, "address of the Swagger UI, unless -single-port serves it on -http-addr")
+	fs.StringVar(&cfg.SwaggerFile, "swagger-file", cfg.SwaggerFile, "OpenAPI document served by the Swagger UI")
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", cfg.MetricsAddr, "admin address serving Prometheus metrics on /metrics, also in -single-port mode; empty disables metrics")
+	fs.BoolVar(&cfg.SinglePort, "single-port", cfg.SinglePort, "serve gRPC, the REST gateway and the Swagger UI on -http-addr")
+	fs.Func("gateway-mode", "how the HTTP gateway calls the service: inprocess or endpoint (through the gRPC listener) (default "+string(cfg.GatewayMode)+")", func(value string) error {
+		cfg.GatewayMode = GatewayMode(value)
+		return nil
+	})
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "how long in-flight requests may take to complete on shutdown")
+	fs.Func("error-format", "default error response format: json or problem (RFC 9457) (default "+string(cfg.ErrorFormat)+")", func(value string) error {
+		cfg.ErrorFormat = ErrorFormat(value)
+		return nil
+	})
+	fs.TextVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "minimum level of log records: debug, info, warn or error")
+	fs.Func("log-format", "format of the log records: json or text (default "+string(cfg.LogFormat)+")", func(value string) error {
+		cfg.LogFormat = LogFormat(value)
+		return nil
+	})
+	fs.Var(&listValue{list: &cfg.LogRedact}, "log-redact", "comma separated log attribute keys whose values are redacted")
+	fs.Func("access-log-format", "format of the access log: off, common, combined, json or template (default "+string(cfg.AccessLogFormat)+")", func(value string) error {
+		cfg.AccessLogFormat = AccessLogFormat(value)
+		return nil
+	})
+	fs.StringVar(&cfg.AccessLogTemplate, "access-log-template", cfg.AccessLogTemplate, "text/template of -access-log-format template, executed with the fields of AccessLogEntry")
+	fs.StringVar(&cfg.AccessLogFile, "access-log-file", cfg.AccessLogFile, "file the access log is appended to; stdout when empty")
+	fs.Var(&listValue{list: &cfg.TrustedProxies}, "trusted-proxies", "comma separated IP addresses and CIDR ranges of the proxies whose X-Forwarded-For header gives the client IP of the access log and the per-IP rate limit; the gateway's own hop in -gateway-mode endpoint is always trusted")
+	fs.BoolVar(&cfg.LogErrors, "log-errors", cfg.LogErrors, "log the errors returned to clients")
+	fs.BoolVar(&cfg.ExposePanicDetails, "expose-panic-details", cfg.ExposePanicDetails, "include the panic message and stack trace in error responses")
+	fs.BoolVar(&cfg.WatchConfig, "watch-config", cfg.WatchConfig, "reload the configuration and API key files when they change; SIGHUP always reloads")
+	fs.Func("trace-exporter", "where spans are exported: none, otlp, stdout or file (default "+string(cfg.TraceExporter)+")", func(value string) error {
+		cfg.TraceExporter = TraceExporter(value)
+		return nil
+	})
+	fs.StringVar(&cfg.TraceOTLPEndpoint, "trace-otlp-endpoint", cfg.TraceOTLPEndpoint, "URL of the OTLP/gRPC collector of -trace-exporter otlp, e.g. http://localhost:4317; $OTEL_EXPORTER_OTLP_ENDPOINT applies when empty")
+	fs.StringVar(&cfg.TraceFile, "trace-file", cfg.TraceFile, "file -trace-exporter file appends spans to")
+	fs.Float64Var(&cfg.TraceSampleRatio, "trace-sample-ratio", cfg.TraceSampleRatio, "fraction of new traces recorded, from 0 to 1")
+	fs.Func("store", "storage backend for unstructured data: memory or bolt (default "+string(cfg.StoreKind)+")", func(value string) error {
+		cfg.StoreKind = StoreKind(value)
+		return nil
+	})
+	fs.StringVar(&cfg.StorePath, "store-path", cfg.StorePath, "database file of the bolt store")
+	fs.StringVar(&cfg.PageTokenSecret, "page-token-secret", cfg.PageTokenSecret, "secret keying list page tokens; a random key is used when empty, invalidating tokens on restart")
+	fs.DurationVar(&cfg.IdempotencyWindow, "idempotency-window", cfg.IdempotencyWindow, "how long responses are kept for replay of requests with the same Idempotency-Key")
+	fs.IntVar(&cfg.IdempotencyMaxEntries, "idempotency-max-entries", cfg.IdempotencyMaxEntries, "most Idempotency-Key responses kept; the oldest are dropped first")
+	fs.Int64Var(&cfg.IdempotencyMaxBytes, "idempotency-max-bytes", cfg.IdempotencyMaxBytes, "most bytes of Idempotency-Key responses kept; the oldest are dropped first")
+	fs.StringVar(&cfg.JWTHS256Secret, "jwt-hs256-secret", cfg.JWTHS256Secret, "static HS256 key for JWT bearer tokens")
+	fs.StringVar(&cfg.JWTPublicKeyFile, "jwt-public-key", cfg.JWTPublicKeyFile, "PEM file with an RSA (RS256) or EC P-256 (ES256) public key for JWT bearer tokens")
+	fs.StringVar(&cfg.JWTJWKSFile, "jwt-jwks", cfg.JWTJWKSFile, "local JWKS file with the keys for JWT bearer tokens")
+	fs.StringVar(&cfg.JWTIssuer, "jwt-issuer", cfg.JWTIssuer, "required iss claim of JWT bearer tokens")
+	fs.StringVar(&cfg.JWTAudience, "jwt-audience", cfg.JWTAudience, "required aud claim of JWT bearer tokens")
+	fs.DurationVar(&cfg.JWTLeeway, "jwt-leeway", cfg.JWTLeeway, "clock skew allowed when checking the exp and nbf claims")
+	fs.StringVar(&cfg.APIKeysFile, "api-keys-file", cfg.APIKeysFile, "API key file managed with \"server apikey\" (e.g. "+defaultAPIKeyFile+"); X-API-Key authentication is disabled when empty")
+	fs.BoolVar(&cfg.InsecureDisableAuth, "insecure-disable-auth", cfg.InsecureDisableAuth, "start without JWT keys or -api-keys-file, leaving every RPC open to anyone; for local development only")
+	fs.StringVar(&cfg.TenantClaim, "tenant-claim", cfg.TenantClaim, "JWT claim holding the tenant of the caller")
+	fs.BoolVar(&cfg.RequireTenant, "require-tenant", cfg.RequireTenant, "reject calls without a tenant, and credentials without the tenant claim, instead of assigning them to the \""+defaultTenant+"\" tenant")
+	for _, key := range rateLimitKeys {
+		flagName := "rate-limit-" + strings.ReplaceAll(string(key), "_", "-")
+		fs.Func(flagName, rateLimitUsage[key]+", as <requests>/<period>[:<burst>], e.g. 100/m or 10/s:50; unlimited when not set", func(value string) error {
+			limit, err := parseRateLimit(value)
+			if err != nil {
+				return err
+			}
+			cfg.RateLimits[key] = limit
+			return nil
+		})
+	}
+	fs.Var(&listValue{list: &cfg.RequestHeaders.Allow}, "forward-headers", "comma separated request headers forwarded as gRPC metadata, in addition to those the server reads; x-forward-* forwards a prefix")
+	fs.Var(&renameValue{renames: &cfg.RequestHeaders.Rename}, "rename-headers", "comma separated <header>=<metadata key> request headers forwarded under another name")
+	fs.Var(&listValue{list: &cfg.RequestHeaders.Deny}, "deny-headers", "comma separated request headers (or prefixes ending in *) never forwarded")
+	fs.Var(&listValue{list: &cfg.ResponseHeaders.Allow}, "response-headers", "comma separated gRPC response metadata keys written as plain HTTP headers instead of Grpc-Metadata-<key>; x-forward-* matches a prefix")
+	fs.Var(&renameValue{renames: &cfg.ResponseHeaders.Rename}, "rename-response-headers", "comma separated <metadata key>=<header> response metadata written under another header name")
+	fs.Var(&listValue{list: &cfg.ResponseHeaders.Deny}, "deny-response-headers", "comma separated gRPC response metadata keys (or prefixes ending in *) never written as headers")
+
+	if err := applyConfigEnv(fs); err != nil {
+		return nil, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// configFileArg finds the value of the -config flag before the flags are parsed
+func configFileArg(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// readFile overrides the settings with those of a YAML or JSON file.
+// Unknown keys are rejected so that typos do not go unnoticed.
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	// JSON is valid YAML, so one decoder reads both
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyConfigEnv sets the flags that have a DISCOVER_<FLAG> environment variable
+func applyConfigEnv(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		name := configEnvPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		value, ok := os.LookupEnv(name)
+		if !ok || err != nil || f.Name == "config" {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, name, setErr)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Command line flags replace the lists set by the environment rather than adding to them
+	fs.VisitAll(func(f *flag.Flag) {
+		switch v := f.Value.(type) {
+		case *listValue:
+			v.set = false
+		case *renameValue:
+			v.set = false
+		}
+	})
+	return nil
+}
+
+// validate checks the settings
+func (c *Config) validate() error {
+	addrs := c.listenAddrs()
+	for _, addr := range addrs {
+		if _, _, err := net.SplitHostPort(addr.addr); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", addr.name, addr.addr, err)
+		}
+	}
+	for i, a := range addrs {
+		for _, b := range addrs[i+1:] {
+			if addrsCollide(a.addr, b.addr) {
+				return fmt.Errorf("%s %q and %s %q listen on the same port", a.name, a.addr, b.name, b.addr)
+			}
+		}
+	}
+
+	switch c.GatewayMode {
+	case GatewayModeInProcess, GatewayModeEndpoint:
+	default:
+		return fmt.Errorf("unknown gateway mode %q (want %q or %q)", c.GatewayMode, GatewayModeInProcess, GatewayModeEndpoint)
+	}
+
+	switch c.StoreKind {
+	case StoreKindMemory, StoreKindBolt:
+	default:
+		return fmt.Errorf("unknown store %q (want %q or %q)", c.StoreKind, StoreKindMemory, StoreKindBolt)
+	}
+
+	format, err := parseErrorFormat(string(c.ErrorFormat))
+	if err != nil {
+		return err
+	}
+	c.ErrorFormat = format
+
+	logFormat, err := parseLogFormat(string(c.LogFormat))
+	if err != nil {
+		return err
+	}
+	c.LogFormat = logFormat
+
+	accessLogFormat, err := parseAccessLogFormat(string(c.AccessLogFormat))
+	if err != nil {
+		return err
+	}
+	c.AccessLogFormat = accessLogFormat
+	if c.AccessLogFormat == AccessLogTemplate {
+		if _, err := parseAccessLogTemplate(c.AccessLogTemplate); err != nil {
+			return err
+		}
+	}
+	if _, err := parseTrustedProxies(c.TrustedProxies); err != nil {
+		return err
+	}
+
+	traceExporter, err := parseTraceExporter(string(c.TraceExporter))
+	if err != nil {
+		return err
+	}
+	c.TraceExporter = traceExporter
+	if c.TraceExporter == TraceExporterFile && c.TraceFile == "" {
+		return fmt.Errorf("trace file must not be empty with the file trace exporter")
+	}
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		return fmt.Errorf("trace sample ratio must be between 0 and 1, got %v", c.TraceSampleRatio)
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("shutdown timeout must be positive, got %s", c.ShutdownTimeout)
+	}
+
+	if c.IdempotencyWindow <= 0 {
+		return fmt.Errorf("idempotency window must be positive, got %s", c.IdempotencyWindow)
+	}
+	if c.IdempotencyMaxEntries <= 0 || c.IdempotencyMaxBytes <= 0 {
+		return fmt.Errorf("idempotency limits must be positive, got %d entries and %d bytes", c.IdempotencyMaxEntries, c.IdempotencyMaxBytes)
+	}
+
+	if c.JWTLeeway < 0 {
+		return fmt.Errorf("JWT leeway must not be negative, got %s", c.JWTLeeway)
+	}
+
+	if c.InsecureDisableAuth && (c.JWTHS256Secret != "" || c.JWTPublicKeyFile != "" || c.JWTJWKSFile != "" || c.APIKeysFile != "") {
+		return fmt.Errorf("insecure-disable-auth cannot be combined with JWT keys or an API key file")
+	}
+
+	if c.TenantClaim == "" {
+		return fmt.Errorf("tenant claim must not be empty")
+	}
+
+	for key := range c.RateLimits {
+		if _, ok := rateLimitUsage[key]; !ok {
+			return fmt.Errorf("unknown rate limit key %q (want api_key, tenant, ip or route)", key)
+		}
+	}
+
+	if err := c.RequestHeaders.validate(); err != nil {
+		return err
+	}
+	return c.ResponseHeaders.validate()
+}
+
+// listValue is a flag holding a comma separated list.
+// The first use of the flag replaces the list; repeated uses add to it.
+type listValue struct {
+	list *[]string
+	set  bool
+}
+
+func (v *listValue) String() string {
+	if v.list == nil {
+		return ""
+	}
+	return strings.Join(*v.list, ",")
+}
+
+func (v *listValue) Set(value string) error {
+	if !v.set {
+		*v.list = nil
+		v.set = true
+	}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			*v.list = append(*v.list, entry)
+		}
+	}
+	return nil
+}
+
+// renameValue is a flag holding comma separated <from>=<to> pairs.
+// The first use of the flag replaces the pairs; repeated uses add to them.
+type renameValue struct {
+	renames *map[string]string
+	set     bool
+}
+
+func (v *renameValue) String() string {
+	if v.renames == nil {
+		return ""
+	}
+	var pairs []string
+	for from, to := range *v.renames {
+		pairs = append(pairs, from+"="+to)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v *renameValue) Set(value string) error {
+	if !v.set {
+		*v.renames = map[string]string{}
+		v.set = true
+	}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("invalid rename %q: want <from>=<to>", entry)
+		}
+		(*v.renames)[strings.TrimSpace(from)] = strings.TrimSpace(to)
+	}
+	return nil
+}
+
+// rateLimitUsage describes the rate limit flags
+var rateLimitUsage = map[RateLimitKey]string{
+	RateLimitKeyAPIKey: "rate limit per API key",
+	RateLimitKeyTenant: "rate limit per tenant",
+	RateLimitKeyIP:     "rate limit per client IP address",
+	RateLimitKeyRoute:  "rate limit per RPC method, shared by all callers",
+}
+
+// listenAddr is an address the server listens on
+type listenAddr struct {
+	name string
+	addr string
+}
+
+// listenAddrs returns the addresses the server listens on. In single-port mode gRPC and
+// the Swagger UI are served on the HTTP address, so their own addresses are unused.
+func (c *Config) listenAddrs() []listenAddr {
+	addrs := []listenAddr{{"HTTP address", c.HTTPAddr}}
+	if !c.SinglePort {
+		addrs = append(addrs, listenAddr{"gRPC address", c.GRPCAddr}, listenAddr{"Swagger UI address", c.SwaggerAddr})
+	}
+	if c.MetricsAddr != "" {
+		addrs = append(addrs, listenAddr{"metrics address", c.MetricsAddr})
+	}
+	return addrs
+}
+
+// addrsCollide reports whether two listen addresses bind the same port on a common interface.
+// Port 0 picks a free port and never collides.
+func addrsCollide(a, b string) bool {
+	hostA, portA, _ := net.SplitHostPort(a)
+	hostB, portB, _ := net.SplitHostPort(b)
+	if portA != portB || portA == "0" {
+		return false
+	}
+	return hostA == hostB || wildcardHost(hostA) || wildcardHost(hostB)
+}
+
+// wildcardHost reports whether a listen host binds all interfaces
+func wildcardHost(host string) bool {
+	return host == "" || host == "0.0.0.0" || host == "::"
+}
+
+// grpcTarget returns the address the gateway dials to reach the gRPC server
+func (c *Config) grpcTarget() string {
+	if c.SinglePort {
+		return dialTarget(c.HTTPAddr)
+	}
+	return dialTarget(c.GRPCAddr)
+}
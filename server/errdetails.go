@@ -0,0 +1,261 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain is the ErrorInfo domain used for errors raised by this service
+const errorDomain = "discoverservice"
+
+// Type URLs of the google.rpc error details rendered into ErrorResponse.Details
+const (
+	typeBadRequest   = "type.googleapis.com/google.rpc.BadRequest"
+	typeErrorInfo    = "type.googleapis.com/google.rpc.ErrorInfo"
+	typeRetryInfo    = "type.googleapis.com/google.rpc.RetryInfo"
+	typeResourceInfo = "type.googleapis.com/google.rpc.ResourceInfo"
+	typeQuotaFailure = "type.googleapis.com/google.rpc.QuotaFailure"
+
+	typePreconditionFailure = "type.googleapis.com/google.rpc.PreconditionFailure"
+)
+
+// ErrorDetail is a typed entry of ErrorResponse.Details.
+// Clients switch on the "@type" member to decide how to read the entry.
+type ErrorDetail interface {
+	TypeURL() string
+}
+
+// FieldViolation describes a single invalid request field
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// BadRequestDetail lists the request fields that failed validation
+type BadRequestDetail struct {
+	Type            string           `json:"@type"`
+	FieldViolations []FieldViolation `json:"field_violations"`
+}
+
+func (d *BadRequestDetail) TypeURL() string { return d.Type }
+
+// ErrorInfoDetail carries a machine readable reason for the error
+type ErrorInfoDetail struct {
+	Type     string            `json:"@type"`
+	Reason   string            `json:"reason"`
+	Domain   string            `json:"domain"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func (d *ErrorInfoDetail) TypeURL() string { return d.Type }
+
+// RetryInfoDetail tells the client how long to wait before retrying
+type RetryInfoDetail struct {
+	Type       string `json:"@type"`
+	RetryDelay string `json:"retry_delay"`
+}
+
+func (d *RetryInfoDetail) TypeURL() string { return d.Type }
+
+// ResourceInfoDetail identifies the resource the error relates to
+type ResourceInfoDetail struct {
+	Type         string `json:"@type"`
+	ResourceType string `json:"resource_type"`
+	ResourceName string `json:"resource_name"`
+	Owner        string `json:"owner,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+func (d *ResourceInfoDetail) TypeURL() string { return d.Type }
+
+// QuotaViolation describes a single exceeded quota
+type QuotaViolation struct {
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+// QuotaFailureDetail lists the quotas that were exceeded
+type QuotaFailureDetail struct {
+	Type       string           `json:"@type"`
+	Violations []QuotaViolation `json:"violations"`
+}
+
+func (d *QuotaFailureDetail) TypeURL() string { return d.Type }
+
+// PreconditionViolation describes a single failed precondition
+type PreconditionViolation struct {
+	Type        string `json:"type"`
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+// PreconditionFailureDetail lists the preconditions that failed, such as a stale If-Match
+type PreconditionFailureDetail struct {
+	Type       string                  `json:"@type"`
+	Violations []PreconditionViolation `json:"violations"`
+}
+
+func (d *PreconditionFailureDetail) TypeURL() string { return d.Type }
+
+// renderErrorDetails converts the details attached to a gRPC status into ErrorResponse details.
+// Details of unsupported types are skipped.
+func renderErrorDetails(grpcStatus *status.Status) []ErrorDetail {
+	var details []ErrorDetail
+	for _, detail := range grpcStatus.Details() {
+		switch d := detail.(type) {
+		case *errdetails.BadRequest:
+			rendered := &BadRequestDetail{Type: typeBadRequest}
+			for _, v := range d.GetFieldViolations() {
+				rendered.FieldViolations = append(rendered.FieldViolations, FieldViolation{
+					Field:       v.GetField(),
+					Description: v.GetDescription(),
+					Reason:      v.GetReason(),
+				})
+			}
+			details = append(details, rendered)
+		case *errdetails.ErrorInfo:
+			details = append(details, &ErrorInfoDetail{
+				Type:     typeErrorInfo,
+				Reason:   d.GetReason(),
+				Domain:   d.GetDomain(),
+				Metadata: d.GetMetadata(),
+			})
+		case *errdetails.RetryInfo:
+			details = append(details, &RetryInfoDetail{
+				Type:       typeRetryInfo,
+				RetryDelay: d.GetRetryDelay().AsDuration().String(),
+			})
+		case *errdetails.ResourceInfo:
+			details = append(details, &ResourceInfoDetail{
+				Type:         typeResourceInfo,
+				ResourceType: d.GetResourceType(),
+				ResourceName: d.GetResourceName(),
+				Owner:        d.GetOwner(),
+				Description:  d.GetDescription(),
+			})
+		case *errdetails.QuotaFailure:
+			rendered := &QuotaFailureDetail{Type: typeQuotaFailure}
+			for _, v := range d.GetViolations() {
+				rendered.Violations = append(rendered.Violations, QuotaViolation{
+					Subject:     v.GetSubject(),
+					Description: v.GetDescription(),
+				})
+			}
+			details = append(details, rendered)
+		case *errdetails.PreconditionFailure:
+			rendered := &PreconditionFailureDetail{Type: typePreconditionFailure}
+			for _, v := range d.GetViolations() {
+				rendered.Violations = append(rendered.Violations, PreconditionViolation{
+					Type:        v.GetType(),
+					Subject:     v.GetSubject(),
+					Description: v.GetDescription(),
+				})
+			}
+			details = append(details, rendered)
+		}
+	}
+	return details
+}
+
+// statusWithDetails builds a gRPC status error carrying the given details.
+// If the details cannot be attached the plain status is returned.
+func statusWithDetails(c codes.Code, message string, details ...protoadapt.MessageV1) error {
+	st := status.New(c, message)
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// fieldViolation describes why a request field is invalid
+func fieldViolation(field, description string) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{Field: field, Description: description}
+}
+
+// errorInfo builds an ErrorInfo detail in the service domain
+func errorInfo(reason string, metadata map[string]string) *errdetails.ErrorInfo {
+	return &errdetails.ErrorInfo{Reason: reason, Domain: errorDomain, Metadata: metadata}
+}
+
+// resourceInfo builds a ResourceInfo detail
+func resourceInfo(resourceType, resourceName, description string) *errdetails.ResourceInfo {
+	return &errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: resourceName, Description: description}
+}
+
+// invalidArgumentError returns an InvalidArgument error listing the field violations
+func invalidArgumentError(violations ...*errdetails.BadRequest_FieldViolation) error {
+	descriptions := make([]string, 0, len(violations))
+	for _, v := range violations {
+		descriptions = append(descriptions, v.GetDescription())
+	}
+	return statusWithDetails(codes.InvalidArgument, strings.Join(descriptions, "; "),
+		&errdetails.BadRequest{FieldViolations: violations},
+		errorInfo("INVALID_ARGUMENT", nil),
+	)
+}
+
+// notFoundError returns a NotFound error for the given resource
+func notFoundError(resourceType, resourceName string) error {
+	message := resourceType + " with id '" + resourceName + "' not found"
+	return statusWithDetails(codes.NotFound, message,
+		resourceInfo(resourceType, resourceName, message),
+		errorInfo("RESOURCE_NOT_FOUND", nil),
+	)
+}
+
+// alreadyExistsError returns an AlreadyExists error for the given resource
+func alreadyExistsError(resourceType, resourceName string) error {
+	message := resourceType + " with id '" + resourceName + "' already exists"
+	return statusWithDetails(codes.AlreadyExists, message,
+		resourceInfo(resourceType, resourceName, message),
+		errorInfo("RESOURCE_ALREADY_EXISTS", nil),
+	)
+}
+
+// permissionDeniedError returns a PermissionDenied error with the given reason
+func permissionDeniedError(reason, message string, metadata map[string]string) error {
+	return statusWithDetails(codes.PermissionDenied, message, errorInfo(reason, metadata))
+}
+
+// unauthenticatedError returns an Unauthenticated error with the given reason
+func unauthenticatedError(reason, message string) error {
+	return statusWithDetails(codes.Unauthenticated, message, errorInfo(reason, nil))
+}
+
+// resourceExhaustedError returns a ResourceExhausted error for the quota subject,
+// telling the client to retry after the given delay
+func resourceExhaustedError(subject, description string, retryDelay time.Duration) error {
+	return statusWithDetails(codes.ResourceExhausted, description,
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{
+			{Subject: subject, Description: description},
+		}},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)},
+		errorInfo("RATE_LIMIT_EXCEEDED", map[string]string{"subject": subject}),
+	)
+}
+
+// retryDelay returns the delay of the RetryInfo detail of an error, if any
+func retryDelay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0, false
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// internalError returns an Internal error with the given message
+func internalError(message string) error {
+	return statusWithDetails(codes.Internal, message, errorInfo("INTERNAL", nil))
+}
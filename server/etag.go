@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// Metadata keys carrying entity tags between the gateway and the handlers
+const (
+	etagMetadataKey    = "etag"
+	ifMatchMetadataKey = "if-match"
+)
+
+// computeETag returns a strong entity tag for the canonical (deterministic) protobuf encoding of msg
+func computeETag(msg proto.Message) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// setETag sends the entity tag of msg as response metadata, which the gateway turns into the ETag header
+func setETag(ctx context.Context, msg proto.Message) {
+	etag, err := computeETag(msg)
+	if err != nil {
+		return
+	}
+	// Fails only when there is no transport stream to carry the header; the ETag is optional
+	_ = grpc.SetHeader(ctx, metadata.Pairs(etagMetadataKey, etag))
+}
+
+// ifMatchFromContext returns the If-Match condition forwarded with the request, if any
+func ifMatchFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	return strings.Join(md.Get(ifMatchMetadataKey), ",")
+}
+
+// etagMatches reports whether the entity tag matches the list of a conditional header.
+// Weak comparison (RFC 9110 section 8.8.3.2) ignores the W/ prefix, as required for If-None-Match;
+// strong comparison, required for If-Match, never matches weak tags.
+func etagMatches(header, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if candidate == etag && !strings.HasPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch verifies the If-Match condition of the request against the current representation.
+// A stale condition fails with FailedPrecondition, which maps to HTTP 412. The error leaves out
+// the current entity tag, so that clients read the resource again before retrying.
+func checkIfMatch(ctx context.Context, resourceName string, current proto.Message) error {
+	ifMatch := ifMatchFromContext(ctx)
+	if ifMatch == "" {
+		return nil
+	}
+
+	etag, err := computeETag(current)
+	if err != nil {
+		return internalError("failed to compute entity tag")
+	}
+	if etagMatches(ifMatch, etag, false) {
+		return nil
+	}
+
+	message := "If-Match condition " + ifMatch + " does not match the current entity tag of " + unstructuredDataResource + " '" + resourceName + "'"
+	return statusWithDetails(codes.FailedPrecondition, message,
+		&errdetails.PreconditionFailure{Violations: []*errdetails.PreconditionFailure_Violation{
+			{Type: "ETAG", Subject: unstructuredDataResource + "/" + resourceName, Description: "entity tag changed since it was read"},
+		}},
+		errorInfo("ETAG_MISMATCH", nil),
+	)
+}
+
+// ConditionalRequestMiddleware answers GET and HEAD requests whose If-None-Match header matches
+// the ETag of the response with 304 Not Modified and no body
+func ConditionalRequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch := r.Header.Get("If-None-Match")
+		if ifNoneMatch == "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(&conditionalResponseWriter{ResponseWriter: w, ifNoneMatch: ifNoneMatch}, r)
+	})
+}
+
+// conditionalResponseWriter turns a 200 response into 304 when its ETag matches If-None-Match
+type conditionalResponseWriter struct {
+	http.ResponseWriter
+	ifNoneMatch string
+	wroteHeader bool
+	notModified bool
+}
+
+// WriteHeader replaces a 200 status with 304 when the ETag matches
+func (w *conditionalResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if statusCode == http.StatusOK && etagMatches(w.ifNoneMatch, w.Header().Get("ETag"), true) {
+		w.notModified = true
+		w.Header().Del("Content-Type")
+		w.Header().Del("Content-Length")
+		statusCode = http.StatusNotModified
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write discards the body of a 304 response
+func (w *conditionalResponseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.notModified {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *conditionalResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
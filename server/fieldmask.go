@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// wildcardPath selects every field in a field mask (AIP-134, AIP-157)
+const wildcardPath = "*"
+
+// normalizeFieldMask validates the paths of a field mask against the message descriptor.
+// Paths may use proto or JSON field names; the returned paths use proto names.
+// Repeated, map and google.protobuf.Any fields can only appear at the end of a path.
+// Every invalid path is reported as a violation of the request field maskField.
+func normalizeFieldMask(mask *fieldmaskpb.FieldMask, md protoreflect.MessageDescriptor, maskField string) ([]string, []*errdetails.BadRequest_FieldViolation) {
+	var paths []string
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, path := range mask.GetPaths() {
+		path = strings.TrimSpace(path)
+		if path == wildcardPath {
+			paths = append(paths, path)
+			continue
+		}
+		normalized, err := resolveFieldPath(md, path)
+		if err != nil {
+			violations = append(violations, fieldViolation(maskField, err.Error()))
+			continue
+		}
+		paths = append(paths, normalized)
+	}
+	return paths, violations
+}
+
+// resolveFieldPath resolves a dotted field path and returns it with proto field names
+func resolveFieldPath(md protoreflect.MessageDescriptor, path string) (string, error) {
+	segments := strings.Split(path, ".")
+	names := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if md == nil {
+			return "", fmt.Errorf("field path %q: %q is not a message field", path, strings.Join(names, "."))
+		}
+		fd := md.Fields().ByName(protoreflect.Name(segment))
+		if fd == nil {
+			fd = md.Fields().ByJSONName(segment)
+		}
+		if fd == nil {
+			return "", fmt.Errorf("field path %q: unknown field %q in %s", path, segment, md.Name())
+		}
+		names = append(names, string(fd.Name()))
+
+		md = nil
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() && fd.Message().FullName() != "google.protobuf.Any" {
+			md = fd.Message()
+		}
+	}
+	return strings.Join(names, "."), nil
+}
+
+// populatedFieldPaths returns the names of the populated top-level fields of msg.
+// It is the implied update mask when a request does not carry one.
+func populatedFieldPaths(msg proto.Message) []string {
+	var paths []string
+	msg.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		paths = append(paths, string(fd.Name()))
+		return true
+	})
+	return paths
+}
+
+// applyUpdateMask copies the fields listed in paths from src to dst.
+// Fields unset in src are cleared in dst. The paths must have been normalized.
+func applyUpdateMask(dst, src proto.Message, paths []string) {
+	for _, path := range paths {
+		if path == wildcardPath {
+			proto.Reset(dst)
+			proto.Merge(dst, src)
+			continue
+		}
+
+		segments := strings.Split(path, ".")
+		dstMsg, srcMsg := dst.ProtoReflect(), src.ProtoReflect()
+		for _, segment := range segments[:len(segments)-1] {
+			fd := dstMsg.Descriptor().Fields().ByName(protoreflect.Name(segment))
+			dstMsg = dstMsg.Mutable(fd).Message()
+			// An unset parent in src reads as an empty message, which clears the leaf below
+			srcMsg = srcMsg.Get(fd).Message()
+		}
+
+		fd := dstMsg.Descriptor().Fields().ByName(protoreflect.Name(segments[len(segments)-1]))
+		if srcMsg.Has(fd) {
+			dstMsg.Set(fd, srcMsg.Get(fd))
+		} else {
+			dstMsg.Clear(fd)
+		}
+	}
+}
+
+// applyReadMask clears every field of msg not selected by the paths.
+// An empty mask or the wildcard path keeps all fields. The paths must have been normalized.
+func applyReadMask(msg proto.Message, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+
+	tree := fieldPathTree{}
+	for _, path := range paths {
+		if path == wildcardPath {
+			return
+		}
+		tree.add(strings.Split(path, "."))
+	}
+	tree.prune(msg.ProtoReflect())
+}
+
+// fieldPathTree is a set of field paths indexed by segment. A nil subtree selects the whole field.
+type fieldPathTree map[string]fieldPathTree
+
+// add inserts a split path into the tree
+func (t fieldPathTree) add(segments []string) {
+	subtree, ok := t[segments[0]]
+	if ok && subtree == nil {
+		// The whole field is already selected
+		return
+	}
+	if len(segments) == 1 {
+		t[segments[0]] = nil
+		return
+	}
+	if subtree == nil {
+		subtree = fieldPathTree{}
+		t[segments[0]] = subtree
+	}
+	subtree.add(segments[1:])
+}
+
+// prune clears the fields of m that are not in the tree
+func (t fieldPathTree) prune(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		subtree, ok := t[string(fd.Name())]
+		switch {
+		case !ok:
+			m.Clear(fd)
+		case subtree != nil:
+			subtree.prune(v.Message())
+		}
+		return true
+	})
+}
+
+// readMaskKey is the context key marking requests that select fields with a read mask
+type readMaskKey struct{}
+
+// ReadMaskMiddleware marks the requests carrying a read_mask query parameter for SparseResponseRewriter.
+// Register it with runtime.WithMiddlewares.
+func ReadMaskMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		query := r.URL.Query()
+		if query.Has("read_mask") || query.Has("readMask") {
+			r = r.WithContext(context.WithValue(r.Context(), readMaskKey{}, true))
+		}
+		next(w, r, pathParams)
+	}
+}
+
+// SparseResponseRewriter leaves the unpopulated fields out of the JSON responses to requests with
+// a read mask, so that the fields the mask did not select are absent rather than zero values.
+// Register it with runtime.WithForwardResponseRewriter.
+func SparseResponseRewriter(ctx context.Context, response proto.Message) (any, error) {
+	if masked, _ := ctx.Value(readMaskKey{}).(bool); masked {
+		return sparseMessage{message: response}, nil
+	}
+	return response, nil
+}
+
+// sparseMessage is a response marshaled without its unpopulated fields. It is not a proto.Message,
+// so the gateway's marshaler falls back to its MarshalJSON method.
+type sparseMessage struct {
+	message proto.Message
+}
+
+// MarshalJSON implements json.Marshaler
+func (m sparseMessage) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(m.message)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection"
+
+	discoverservicepb "protobuf-http-golang/pb"
+)
+
+// newGRPCServer creates the native gRPC server serving the discover service
+func newGRPCServer(service discoverservicepb.DiscoverServiceServer, interceptors ...grpc.UnaryServerInterceptor) *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	discoverservicepb.RegisterDiscoverServiceServer(grpcServer, service)
+
+	// Allow tools such as grpcurl to discover the service
+	reflection.Register(grpcServer)
+
+	return grpcServer
+}
+
+// registerGateway registers the HTTP handlers on the gateway mux according to the gateway mode.
+// The interceptors must be those of the gRPC server; in-process calls run them explicitly.
+func registerGateway(ctx context.Context, mux *runtime.ServeMux, cfg *Config, service discoverservicepb.DiscoverServiceServer, interceptors ...grpc.UnaryServerInterceptor) error {
+	switch cfg.GatewayMode {
+	case GatewayModeInProcess:
+		// Call the server implementation directly
+		return discoverservicepb.RegisterDiscoverServiceHandlerServer(ctx, mux, newInterceptedServer(service, interceptors...))
+	case GatewayModeEndpoint:
+		// Dial the gRPC listener so that header/metadata forwarding goes through a real gRPC hop
+		opts := []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithChainUnaryInterceptor(UnaryClientTracingInterceptor()),
+		}
+		return discoverservicepb.RegisterDiscoverServiceHandlerFromEndpoint(ctx, mux, cfg.grpcTarget(), opts)
+	default:
+		return fmt.Errorf("unknown gateway mode %q", cfg.GatewayMode)
+	}
+}
+
+// dialTarget turns a listen address such as ":9090" into an address that can be dialed
+func dialTarget(listenAddr string) string {
+	if strings.HasPrefix(listenAddr, ":") {
+		return "localhost" + listenAddr
+	}
+	return listenAddr
+}
+
+// stopGRPCServer stops the gRPC server gracefully, forcing it to stop when ctx expires
+func stopGRPCServer(ctx context.Context, grpcServer *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+// HeaderRules configure which headers are forwarded and under which name.
+// Names are case-insensitive; an entry ending in "*" matches every name with that prefix.
+type HeaderRules struct {
+	// Allow forwards the headers under their own name
+	Allow []string `yaml:"allow"`
+	// Rename forwards a header under another name
+	Rename map[string]string `yaml:"rename"`
+	// Deny never forwards the headers; it overrides Allow, Rename and the built-in headers
+	Deny []string `yaml:"deny"`
+}
+
+// validate checks the entries of the rules
+func (r HeaderRules) validate() error {
+	for _, list := range [][]string{r.Allow, r.Deny} {
+		for _, entry := range list {
+			if strings.TrimSuffix(entry, "*") == "" || strings.Contains(strings.TrimSuffix(entry, "*"), "*") {
+				return fmt.Errorf("invalid header rule %q: want a header name or a prefix ending in *", entry)
+			}
+		}
+	}
+	for from, to := range r.Rename {
+		if from == "" || to == "" || strings.Contains(from+to, "*") {
+			return fmt.Errorf("invalid header rename %q=%q: want <header>=<name>", from, to)
+		}
+	}
+	return nil
+}
+
+// incomingHeaders are the request headers the server itself reads, forwarded as metadata
+// whatever the configured rules allow. Headers bound to request fields with (bind) are added in main.
+var incomingHeaders = map[string]string{
+	"authorization": authorizationMetadataKey,
+	"content-type":  "content-type",
+	"if-match":      ifMatchMetadataKey,
+	"x-api-key":     apiKeyMetadataKey,
+	"traceparent":   traceparentMetadataKey,
+	"x-request-id":  requestIDMetadataKey,
+	"x-tenant-id":   tenantMetadataKey,
+}
+
+// outgoingHeaders are the response metadata keys the server sets, written as standard headers
+var outgoingHeaders = map[string]string{
+	etagMetadataKey:               "ETag",
+	rateLimitLimitMetadataKey:     "RateLimit-Limit",
+	rateLimitRemainingMetadataKey: "RateLimit-Remaining",
+	requestIDMetadataKey:          requestIDHeader,
+}
+
+// headerMatcher maps header names according to HeaderRules
+type headerMatcher struct {
+	names        map[string]string
+	prefixes     []string
+	deny         map[string]bool
+	denyPrefixes []string
+	// name returns the forwarded name of a header matched by a prefix
+	name func(key string) string
+	// fallback maps the headers no rule matches
+	fallback func(key string) (string, bool)
+}
+
+// newHeaderMatcher applies the rules on top of the built-in mappings
+func newHeaderMatcher(rules HeaderRules, builtin map[string]string, name func(string) string, fallback func(string) (string, bool)) *headerMatcher {
+	m := &headerMatcher{
+		names:    make(map[string]string, len(builtin)),
+		deny:     make(map[string]bool),
+		name:     name,
+		fallback: fallback,
+	}
+	for key, target := range builtin {
+		m.names[key] = target
+	}
+	for _, entry := range rules.Allow {
+		entry = strings.ToLower(entry)
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+			m.prefixes = append(m.prefixes, prefix)
+		} else {
+			m.names[entry] = name(entry)
+		}
+	}
+	for from, to := range rules.Rename {
+		m.names[strings.ToLower(from)] = to
+	}
+	for _, entry := range rules.Deny {
+		entry = strings.ToLower(entry)
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+			m.denyPrefixes = append(m.denyPrefixes, prefix)
+		} else {
+			m.deny[entry] = true
+		}
+	}
+	return m
+}
+
+// match returns the forwarded name of a header, or false to drop it
+func (m *headerMatcher) match(key string) (string, bool) {
+	key = strings.ToLower(key)
+	if m.deny[key] || hasAnyPrefix(key, m.denyPrefixes) {
+		return "", false
+	}
+	if target, ok := m.names[key]; ok {
+		return target, true
+	}
+	if hasAnyPrefix(key, m.prefixes) {
+		return m.name(key), true
+	}
+	return m.fallback(key)
+}
+
+// hasAnyPrefix reports whether key starts with one of the prefixes
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IncomingHeaderMatcher returns a runtime.HeaderMatcherFunc forwarding the built-in request headers
+// and those allowed by the rules as gRPC metadata. Other headers are dropped.
+func IncomingHeaderMatcher(rules HeaderRules) runtime.HeaderMatcherFunc {
+	m := newHeaderMatcher(rules, incomingHeaders,
+		func(key string) string { return key },
+		func(string) (string, bool) { return "", false },
+	)
+	// Renamed metadata keys must be lowercase
+	for key, target := range m.names {
+		m.names[key] = strings.ToLower(target)
+	}
+	// Only the gateway itself sets the client IP it resolved
+	m.deny[gatewayClientIPMetadataKey] = true
+	return m.match
+}
+
+// transportMetadata are the metadata keys of the gRPC transport rather than of the service.
+// Keys starting with "grpc-" are reserved for gRPC as well.
+var transportMetadata = map[string]bool{
+	"content-type": true,
+	"te":           true,
+	"trailer":      true,
+}
+
+// isTransportMetadata reports whether key is set by the gRPC transport
+func isTransportMetadata(key string) bool {
+	return transportMetadata[key] || strings.HasPrefix(key, "grpc-")
+}
+
+// OutgoingHeaderMatcher returns a runtime.HeaderMatcherFunc writing the built-in response metadata
+// and the metadata allowed by the rules as plain HTTP headers. Other metadata keeps the
+// gateway's default Grpc-Metadata- prefix, except the transport metadata, which is dropped.
+func OutgoingHeaderMatcher(rules HeaderRules) runtime.HeaderMatcherFunc {
+	m := newHeaderMatcher(rules, outgoingHeaders,
+		http.CanonicalHeaderKey,
+		func(key string) (string, bool) {
+			if isTransportMetadata(key) {
+				return "", false
+			}
+			return runtime.MetadataHeaderPrefix + key, true
+		},
+	)
+	return m.match
+}
+
+// ReloadableHeaderMatcher is a header matcher whose rules can be replaced while serving.
+// Register its Match method with the mux.
+type ReloadableHeaderMatcher struct {
+	current atomic.Pointer[runtime.HeaderMatcherFunc]
+}
+
+// NewReloadableHeaderMatcher creates a matcher delegating to match
+func NewReloadableHeaderMatcher(match runtime.HeaderMatcherFunc) *ReloadableHeaderMatcher {
+	m := &ReloadableHeaderMatcher{}
+	m.Store(match)
+	return m
+}
+
+// Store replaces the matcher used for the following requests
+func (m *ReloadableHeaderMatcher) Store(match runtime.HeaderMatcherFunc) {
+	m.current.Store(&match)
+}
+
+// Match implements runtime.HeaderMatcherFunc
+func (m *ReloadableHeaderMatcher) Match(key string) (string, bool) {
+	return (*m.current.Load())(key)
+}
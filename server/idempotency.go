@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// idempotencyKeyHeader carries the client chosen key of a retryable request
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks responses replayed from the idempotency cache
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength bounds the size of the keys kept in memory
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyEntry is the state of one idempotency key
+type idempotencyEntry struct {
+	key         string
+	fingerprint [sha256.Size]byte
+	expires     time.Time
+	// done is false while the first request with the key is being processed
+	done   bool
+	status int
+	header http.Header
+	body   []byte
+	// requestID is the ID of the request that produced the response
+	requestID string
+	// size is the memory counted against the byte limit of the cache
+	size int64
+}
+
+// IdempotencyCache remembers the responses of requests carrying an Idempotency-Key
+// for a fixed window. Entries are kept in memory, so they do not survive a restart.
+// When the entry or byte limit is reached the oldest responses are dropped first.
+type IdempotencyCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds the entries from oldest to newest; as the window is fixed, they expire in that order
+	order      *list.List
+	window     time.Duration
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+}
+
+// NewIdempotencyCache creates a cache keeping responses for the given window,
+// holding at most maxEntries keys and maxBytes of responses
+func NewIdempotencyCache(window time.Duration, maxEntries int, maxBytes int64) *IdempotencyCache {
+	return &IdempotencyCache{
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		window:     window,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+	}
+}
+
+// begin looks up the key. If it is unknown (or expired) it is reserved for the caller and
+// begin returns nil, true; otherwise a copy of the existing entry is returned.
+func (c *IdempotencyCache) begin(key string, fingerprint [sha256.Size]byte) (*idempotencyEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.sweep(now)
+
+	if element, ok := c.entries[key]; ok {
+		existing := *element.Value.(*idempotencyEntry)
+		return &existing, false
+	}
+
+	entry := &idempotencyEntry{key: key, fingerprint: fingerprint, expires: now.Add(c.window), size: int64(len(key))}
+	c.entries[key] = c.order.PushBack(entry)
+	c.bytes += entry.size
+	c.evict()
+	return nil, true
+}
+
+// complete stores the response of the request that reserved the key.
+// It reports false, forgetting the key, when the response alone exceeds the byte limit.
+func (c *IdempotencyCache) complete(key, requestID string, status int, header http.Header, body []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	entry := element.Value.(*idempotencyEntry)
+	size := int64(len(key) + len(body))
+	for name, values := range header {
+		size += int64(len(name))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+	if size > c.maxBytes {
+		c.remove(element)
+		return false
+	}
+
+	entry.done = true
+	entry.status = status
+	entry.header = header
+	entry.body = body
+	entry.requestID = requestID
+	c.bytes += size - entry.size
+	entry.size = size
+	c.evict()
+	return true
+}
+
+// release forgets a reserved key so that the request can be retried
+func (c *IdempotencyCache) release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+// sweep drops the expired entries. The caller holds c.mu.
+func (c *IdempotencyCache) sweep(now time.Time) {
+	for element := c.order.Front(); element != nil; element = c.order.Front() {
+		if now.Before(element.Value.(*idempotencyEntry).expires) {
+			return
+		}
+		c.remove(element)
+	}
+}
+
+// evict drops the oldest stored responses until the cache is within its limits.
+// Keys of requests still being processed are kept. The caller holds c.mu.
+func (c *IdempotencyCache) evict() {
+	element := c.order.Front()
+	for element != nil && (len(c.entries) > c.maxEntries || c.bytes > c.maxBytes) {
+		next := element.Next()
+		if element.Value.(*idempotencyEntry).done {
+			c.remove(element)
+		}
+		element = next
+	}
+}
+
+// remove drops an entry. The caller holds c.mu.
+func (c *IdempotencyCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*idempotencyEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size
+}
+
+// idempotentStatus reports whether a response status is stored for replay. Server errors are
+// left out so that the request can be retried, and so are the answers that depend on the
+// moment rather than on the request: authentication, authorization, conflicts and rate limits.
+func idempotentStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict, http.StatusTooManyRequests:
+		return false
+	}
+	return status < http.StatusInternalServerError
+}
+
+// IdempotencyMiddleware makes POST requests to the given paths safe to retry.
+// The first request with an Idempotency-Key header is processed and its response is stored;
+// retries with the same key and body replay that response, the same key with a different
+// body is rejected with 422 and a retry racing the first request is rejected with 409.
+// Only responses written by the handler with a status kept by idempotentStatus are stored.
+// Keys are scoped to the tenant returned by resolveTenant; requests whose tenant cannot be
+// resolved are passed on unchanged, to be rejected by the service.
+// Only the HTTP gateway is covered; native gRPC clients do not send the header.
+func IdempotencyMiddleware(errorHandler ErrorHandler, cache *IdempotencyCache, resolveTenant func(*http.Request) (string, error), paths ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, present := r.Header[idempotencyKeyHeader]
+			if r.Method != http.MethodPost || !present || !slices.Contains(paths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			writeError := func(err error) {
+				response := errorHandler.HandleError(r.Context(), err, r)
+				writeErrorResponse(w, response, errorFormatFor(errorHandler, r), r)
+			}
+
+			key := r.Header.Get(idempotencyKeyHeader)
+			if key == "" || len(key) > maxIdempotencyKeyLength {
+				writeError(invalidArgumentError(fieldViolation(idempotencyKeyHeader, "Idempotency-Key must be between 1 and 255 characters")))
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(invalidArgumentError(fieldViolation("body", "failed to read request body")))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			tenant, err := resolveTenant(r)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Keys are scoped to the route, tenant and caller; the fingerprint covers everything else that selects the outcome
+			scope := idempotencyScope(r, tenant, key)
+			fingerprint := sha256.Sum256([]byte(r.URL.RawQuery + "\n" + string(body)))
+
+			existing, reserved := cache.begin(scope, fingerprint)
+			switch {
+			case reserved:
+			case existing.fingerprint != fingerprint:
+				writeError(&runtime.HTTPStatusError{
+					HTTPStatus: http.StatusUnprocessableEntity,
+					Err: statusWithDetails(codes.InvalidArgument,
+						"Idempotency-Key '"+key+"' was already used with a different request body",
+						errorInfo("IDEMPOTENCY_KEY_REUSED", map[string]string{"idempotency_key": key}),
+					),
+				})
+				return
+			case !existing.done:
+				writeError(statusWithDetails(codes.Aborted,
+					"a request with Idempotency-Key '"+key+"' is still being processed",
+					errorInfo("IDEMPOTENCY_KEY_IN_USE", map[string]string{"idempotency_key": key}),
+				))
+				return
+			default:
+				for name, values := range existing.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set(idempotentReplayedHeader, "true")
+				body := replayedBody(existing, requestIDFromContext(r.Context()))
+				w.Header().Del("Content-Length")
+				w.WriteHeader(existing.status)
+				w.Write(body)
+				return
+			}
+
+			recorder := &idempotencyRecorder{ResponseWriter: w}
+			completed := false
+			defer func() {
+				// Release the key when the handler panicked or its response is not stored
+				if !completed {
+					cache.release(scope)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status != 0 && idempotentStatus(recorder.status) {
+				completed = cache.complete(scope, requestIDFromContext(r.Context()), recorder.status, recorder.header, recorder.body.Bytes())
+			}
+		})
+	}
+}
+
+// replayedBody returns the stored response body for the request with the given ID. Error responses
+// name the request ID in their metadata, which is replaced so that it matches the X-Request-ID
+// header of the replay. Request IDs contain no characters escaped in JSON.
+func replayedBody(entry *idempotencyEntry, requestID string) []byte {
+	if entry.status < http.StatusBadRequest || entry.requestID == "" || requestID == "" {
+		return entry.body
+	}
+	return bytes.Replace(entry.body,
+		[]byte(`"request_id":"`+entry.requestID+`"`),
+		[]byte(`"request_id":"`+requestID+`"`), 1)
+}
+
+// idempotencyScope identifies the key among those of other routes, tenants and callers,
+// so that one caller cannot replay the response stored for another
+func idempotencyScope(r *http.Request, tenant, key string) string {
+	credentials := sha256.Sum256([]byte(r.Header.Get("Authorization") + "\n" + r.Header.Get("X-API-Key")))
+	return strings.Join([]string{
+		r.Method,
+		r.URL.Path,
+		tenant,
+		hex.EncodeToString(credentials[:]),
+		key,
+	}, " ")
+}
+
+// idempotencyRecorder copies the response it writes so that it can be replayed
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code and a snapshot of the headers
+func (w *idempotencyRecorder) WriteHeader(statusCode int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = statusCode
+	w.header = w.Header().Clone()
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the response body
+func (w *idempotencyRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	discoverservicepb "protobuf-http-golang/pb"
+)
+
+// chainUnaryInterceptors combines interceptors into one; the first interceptor is the outermost
+func chainUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// interceptedServer runs unary interceptors around a DiscoverServiceServer.
+// The in-process gateway calls the service directly, bypassing the interceptors of the
+// gRPC server; wrapping the service gives both paths the same authentication and policies.
+// Methods without a wrapper fall back to Unimplemented rather than skipping the interceptors.
+type interceptedServer struct {
+	discoverservicepb.UnimplementedDiscoverServiceServer
+	next        discoverservicepb.DiscoverServiceServer
+	interceptor grpc.UnaryServerInterceptor
+}
+
+// newInterceptedServer wraps service with the interceptors, or returns it as is when there are none
+func newInterceptedServer(service discoverservicepb.DiscoverServiceServer, interceptors ...grpc.UnaryServerInterceptor) discoverservicepb.DiscoverServiceServer {
+	if len(interceptors) == 0 {
+		return service
+	}
+	return &interceptedServer{next: service, interceptor: chainUnaryInterceptors(interceptors...)}
+}
+
+// intercept calls method through the interceptor chain
+func intercept[Req, Resp any](s *interceptedServer, ctx context.Context, fullMethod string, req Req, method func(context.Context, Req) (Resp, error)) (Resp, error) {
+	handler := func(ctx context.Context, req any) (any, error) {
+		return method(ctx, req.(Req))
+	}
+	resp, err := s.interceptor(ctx, req, &grpc.UnaryServerInfo{Server: s.next, FullMethod: fullMethod}, handler)
+	typed, _ := resp.(Resp)
+	return typed, err
+}
+
+func (s *interceptedServer) GetParamInBody(ctx context.Context, req *discoverservicepb.GetParamInBodyRequest) (*discoverservicepb.Response, error) {
+	return intercept(s, ctx, discoverservicepb.DiscoverService_GetParamInBody_FullMethodName, req, s.next.GetParamInBody)
+}
+
+func (s *interceptedServer) GetParamInHeader(ctx context.Context, req *discoverservicepb.GetParamInHeaderRequest) (*discoverservicepb.Response, error) {
+	return intercept(s, ctx, discoverservicepb.DiscoverService_GetParamInHeader_FullMethodName, req, s.next.GetParamInHeader)
+}
+
+func (s *interceptedServer) PostUnstructuredData(ctx context.Context, req *discoverservicepb.PostUnstructuredDataRequest) (*discoverservicepb.PostUnstructuredDataResponse, error) {
+	return intercept(s, ctx, discoverservicepb.DiscoverService_PostUnstructuredData_FullMethodName, req, s.next.PostUnstructuredData)
+}
+
+func (s *interceptedServer) GetUnstructuredData(ctx context.Context, req *discoverservicepb.GetUnstructuredDataRequest) (*discoverservicepb.UnstructuredData, error) {
+	return intercept(s, ctx, discoverservicepb.DiscoverService_GetUnstructuredData_FullMethodName, req, s.next.GetUnstructuredData)
+}
+
+func (s *interceptedServer) ListUnstructuredData(ctx context.Context, req *discoverservicepb.ListUnstructuredDataRequest) (*discoverservicepb.ListUnstructuredDataResponse, error) {
+	return intercept(s, ctx, discoverservicepb.DiscoverService_ListUnstructuredData_FullMethodName, req, s.next.ListUnstructuredData)
+}
+
+func (s *interceptedServer) UpdateUnstructuredData(ctx context.Context, req *discoverservicepb.UpdateUnstructuredDataRequest) (*discoverservicepb.UnstructuredData, error) {
+	return intercept(s, ctx, discoverservicepb.DiscoverService_UpdateUnstructuredData_FullMethodName, req, s.next.UpdateUnstructuredData)
+}
+
+func (s *interceptedServer) DeleteUnstructuredData(ctx context.Context, req *discoverservicepb.DeleteUnstructuredDataRequest) (*emptypb.Empty, error) {
+	return intercept(s, ctx, discoverservicepb.DiscoverService_DeleteUnstructuredData_FullMethodName, req, s.next.DeleteUnstructuredData)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/metadata"
+)
+
+// authorizationMetadataKey is the metadata key the gateway forwards the Authorization header as
+const authorizationMetadataKey = "authorization"
+
+// jwtAlgorithms are the signing algorithms accepted for bearer tokens
+var jwtAlgorithms = []string{
+	jwt.SigningMethodHS256.Alg(),
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodES256.Alg(),
+}
+
+// jwtKey is a key that can verify tokens signed with one algorithm
+type jwtKey struct {
+	// id matches the "kid" token header; empty matches any token
+	id        string
+	algorithm string
+	key       any
+}
+
+// JWTVerifier verifies JWT bearer tokens against a set of static or JWKS keys
+type JWTVerifier struct {
+	mu     sync.RWMutex
+	keys   []jwtKey
+	parser *jwt.Parser
+}
+
+// newJWTVerifier creates a verifier from the JWT settings of cfg.
+// It returns nil when no key is configured, which disables authentication.
+func newJWTVerifier(cfg *Config) (*JWTVerifier, error) {
+	var keys []jwtKey
+	if cfg.JWTHS256Secret != "" {
+		keys = append(keys, jwtKey{algorithm: jwt.SigningMethodHS256.Alg(), key: []byte(cfg.JWTHS256Secret)})
+	}
+	if cfg.JWTPublicKeyFile != "" {
+		key, err := loadJWTPublicKey(cfg.JWTPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if cfg.JWTJWKSFile != "" {
+		jwksKeys, err := loadJWKS(cfg.JWTJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwksKeys...)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtAlgorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.JWTLeeway),
+	}
+	if cfg.JWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if cfg.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(cfg.JWTAudience))
+	}
+
+	return &JWTVerifier{keys: keys, parser: jwt.NewParser(options...)}, nil
+}
+
+// Verify parses a token and checks its signature and its exp, nbf, iss and aud claims
+func (v *JWTVerifier) Verify(tokenString string) (jwt.MapClaims, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(tokenString, claims, v.keyFunc); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// replace switches to the keys and settings of another verifier, for tokens verified from now on
+func (v *JWTVerifier) replace(other *JWTVerifier) {
+	other.mu.RLock()
+	keys, parser := other.keys, other.parser
+	other.mu.RUnlock()
+
+	v.mu.Lock()
+	v.keys, v.parser = keys, parser
+	v.mu.Unlock()
+}
+
+// keyFunc selects the keys matching the algorithm and key id of the token.
+// It runs within Verify, which holds v.mu.
+func (v *JWTVerifier) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	algorithm := token.Method.Alg()
+
+	var set jwt.VerificationKeySet
+	for _, key := range v.keys {
+		if key.algorithm != algorithm || (kid != "" && key.id != "" && key.id != kid) {
+			continue
+		}
+		set.Keys = append(set.Keys, key.key)
+	}
+	if len(set.Keys) == 0 {
+		if kid == "" {
+			return nil, fmt.Errorf("no %s verification key", algorithm)
+		}
+		return nil, fmt.Errorf("no %s verification key with id %q", algorithm, kid)
+	}
+	return set, nil
+}
+
+// authenticate verifies the bearer token in the request metadata
+func (v *JWTVerifier) authenticate(ctx context.Context) (jwt.MapClaims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authorizationMetadataKey)
+	if len(values) == 0 {
+		return nil, unauthenticatedError("MISSING_CREDENTIALS", "authorization header with a bearer token is required")
+	}
+
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return nil, unauthenticatedError("INVALID_TOKEN", "authorization header must use the Bearer scheme")
+	}
+
+	claims, err := v.Verify(strings.TrimSpace(token))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, unauthenticatedError("TOKEN_EXPIRED", "bearer token is expired")
+		}
+		return nil, unauthenticatedError("INVALID_TOKEN", "invalid bearer token: "+err.Error())
+	}
+	return claims, nil
+}
+
+// loadJWTPublicKey reads a PEM encoded RSA (RS256) or P-256 EC (ES256) public key
+func loadJWTPublicKey(path string) (jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return jwtKey{}, fmt.Errorf("read JWT public key: %w", err)
+	}
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return jwtKey{algorithm: jwt.SigningMethodRS256.Alg(), key: key}, nil
+	}
+	key, err := jwt.ParseECPublicKeyFromPEM(data)
+	if err != nil {
+		return jwtKey{}, fmt.Errorf("JWT public key %s is neither an RSA nor an EC public key", path)
+	}
+	if key.Curve != elliptic.P256() {
+		return jwtKey{}, fmt.Errorf("JWT public key %s: only P-256 EC keys (ES256) are supported", path)
+	}
+	return jwtKey{algorithm: jwt.SigningMethodES256.Alg(), key: key}, nil
+}
+
+// jsonWebKey is a key of a JSON Web Key Set (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// Symmetric
+	K string `json:"k"`
+}
+
+// loadJWKS reads the signature keys of a JWKS file.
+// Keys of other types, curves or algorithms are skipped.
+func loadJWKS(path string) ([]jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read JWKS: %w", err)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse JWKS %s: %w", path, err)
+	}
+
+	var keys []jwtKey
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.verificationKey()
+		if err != nil {
+			slog.Warn("Skipping JWKS key", "kid", jwk.Kid, "error", err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS %s has no usable signature keys", path)
+	}
+	return keys, nil
+}
+
+// verificationKey decodes the key for the algorithm implied by its type
+func (k *jsonWebKey) verificationKey() (jwtKey, error) {
+	var algorithm string
+	var key any
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64URL(k.N)
+		if err != nil {
+			return jwtKey{}, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBase64URL(k.E)
+		if err != nil {
+			return jwtKey{}, fmt.Errorf("exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return jwtKey{}, errors.New("exponent is too large")
+		}
+		algorithm = jwt.SigningMethodRS256.Alg()
+		key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	case "EC":
+		if k.Crv != "P-256" {
+			return jwtKey{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBase64URL(k.X)
+		if err != nil {
+			return jwtKey{}, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBase64URL(k.Y)
+		if err != nil {
+			return jwtKey{}, fmt.Errorf("y: %w", err)
+		}
+		// Reject points that are not on the curve
+		point := append([]byte{4}, append(leftPad(x, 32), leftPad(y, 32)...)...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return jwtKey{}, err
+		}
+		algorithm = jwt.SigningMethodES256.Alg()
+		key = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	case "oct":
+		secret, err := decodeBase64URL(k.K)
+		if err != nil {
+			return jwtKey{}, fmt.Errorf("k: %w", err)
+		}
+		algorithm = jwt.SigningMethodHS256.Alg()
+		key = secret
+	default:
+		return jwtKey{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	if k.Alg != "" && k.Alg != algorithm {
+		return jwtKey{}, fmt.Errorf("unsupported algorithm %q for key type %s", k.Alg, k.Kty)
+	}
+	return jwtKey{id: k.Kid, algorithm: algorithm, key: key}, nil
+}
+
+// decodeBase64URL decodes an unpadded base64url value, as used by JWKS
+func decodeBase64URL(value string) ([]byte, error) {
+	if value == "" {
+		return nil, errors.New("missing value")
+	}
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// leftPad pads b with leading zeros to size bytes
+func leftPad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// LogFormat selects how log records are written
+type LogFormat string
+
+const (
+	// LogFormatJSON writes one JSON object per record, for log pipelines
+	LogFormatJSON LogFormat = "json"
+	// LogFormatText writes key=value records, for reading in a terminal
+	LogFormatText LogFormat = "text"
+)
+
+// redactedValue replaces the value of redacted log attributes
+const redactedValue = "[REDACTED]"
+
+// defaultLogRedact are the log attribute keys redacted unless configured otherwise
+var defaultLogRedact = []string{"authorization", "cookie", "x-api-key", "api_key", "password", "secret", "token"}
+
+// LogRedactor replaces the values of log attributes with sensitive keys.
+// Keys are compared case-insensitively and can be replaced while serving.
+type LogRedactor struct {
+	keys atomic.Pointer[map[string]bool]
+}
+
+// NewLogRedactor creates a redactor for the keys
+func NewLogRedactor(keys []string) *LogRedactor {
+	r := &LogRedactor{}
+	r.SetKeys(keys)
+	return r
+}
+
+// SetKeys replaces the redacted keys
+func (r *LogRedactor) SetKeys(keys []string) {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[strings.ToLower(key)] = true
+	}
+	r.keys.Store(&set)
+}
+
+// ReplaceAttr implements slog.HandlerOptions.ReplaceAttr
+func (r *LogRedactor) ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if (*r.keys.Load())[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redactedValue)
+	}
+	return a
+}
+
+// newLogHandler creates the handler writing records of at least level to w
+func newLogHandler(w io.Writer, format LogFormat, level slog.Leveler, redactor *LogRedactor) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactor.ReplaceAttr}
+	if format == LogFormatText {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
+
+// parseLogFormat parses a log format name
+func parseLogFormat(name string) (LogFormat, error) {
+	switch format := LogFormat(strings.ToLower(name)); format {
+	case LogFormatJSON, LogFormatText:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q (want %q or %q)", name, LogFormatJSON, LogFormatText)
+	}
+}
+
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// requestLog is the request-scoped logger. The layers handling a request add their
+// fields to it, so that every record written for the request carries all of them.
+type requestLog struct {
+	mu    sync.Mutex
+	attrs []any
+}
+
+// requestLogKey is the context key of the requestLog
+type requestLogKey struct{}
+
+// withRequestLog returns a context carrying a request-scoped logger with the attributes
+func withRequestLog(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, requestLogKey{}, &requestLog{attrs: args})
+}
+
+// LoggerFromContext returns the request-scoped logger of ctx, or the default logger outside requests
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	l, ok := ctx.Value(requestLogKey{}).(*requestLog)
+	if !ok {
+		return slog.Default()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slog.Default().With(l.attrs...)
+}
+
+// setLogAttr sets a field of the request-scoped logger of ctx, replacing its previous value
+func setLogAttr(ctx context.Context, key string, value any) {
+	l, ok := ctx.Value(requestLogKey{}).(*requestLog)
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := 0; i+1 < len(l.attrs); i += 2 {
+		if l.attrs[i] == key {
+			l.attrs[i+1] = value
+			return
+		}
+	}
+	l.attrs = append(slices.Clip(l.attrs), key, value)
+}
+
+// traceAttrs returns the trace and span IDs of the span of ctx, if it is recorded
+func traceAttrs(ctx context.Context) []any {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() || !spanContext.IsSampled() {
+		return nil
+	}
+	return []any{"trace_id", spanContext.TraceID().String(), "span_id", spanContext.SpanID().String()}
+}
+
+// LoggingMiddleware gives each HTTP request a request-scoped logger (see LoggerFromContext)
+// with the request ID, method and path. It must run inside RequestIDMiddleware.
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var attrs []any
+		if requestID := requestIDFromContext(r.Context()); requestID != "" {
+			attrs = append(attrs, "request_id", requestID)
+		}
+		attrs = append(attrs, traceAttrs(r.Context())...)
+		attrs = append(attrs, "http_method", r.Method, "path", r.URL.Path)
+		next.ServeHTTP(w, r.WithContext(withRequestLog(r.Context(), attrs...)))
+	})
+}
+
+// UnaryLoggingInterceptor adds the RPC method to the request-scoped logger and logs the code and
+// latency of each call. Calls from the in-process gateway keep the logger of their HTTP request;
+// other calls get one with their request ID and trace. It must run right after UnaryTracingInterceptor.
+func UnaryLoggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		if _, ok := ctx.Value(requestLogKey{}).(*requestLog); !ok {
+			ctx = withRequestLog(ctx, append([]any{"request_id", requestIDFromContext(ctx)}, traceAttrs(ctx)...)...)
+		}
+		setLogAttr(ctx, "rpc_method", info.FullMethod)
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		level := slog.LevelInfo
+		if grpcStatusToHTTPStatus(code) >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		LoggerFromContext(ctx).Log(ctx, level, "RPC finished",
+			"code", code.String(),
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+		)
+		return resp, err
+	}
+}
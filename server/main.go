@@ -2,106 +2,340 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
+	"slices"
 	"syscall"
-	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
 
 	discoverservicepb "protobuf-http-golang/pb"
 )
 
-// customHeaderMatcher is a function that determines which HTTP headers should be forwarded as gRPC metadata
-func customHeaderMatcher(key string) (string, bool) {
-	// Convert HTTP header names to gRPC metadata keys
-	// gRPC metadata keys are typically lowercase
-	switch strings.ToLower(key) {
-	case "x-custom-header-id":
-		return "x-custom-header-id", true
-	case "authorization":
-		return "authorization", true
-	case "content-type":
-		return "content-type", true
-	case "x-request-id":
-		return "x-request-id", true
-	default:
-		// Return false for headers we don't want to forward
-		return "", false
+// newErrorHandler creates the error handler configured by cfg
+func newErrorHandler(cfg *Config) *CustomErrorHandler {
+	return &CustomErrorHandler{
+		DefaultErrorHandler: DefaultErrorHandler{OmitPanicDetails: !cfg.ExposePanicDetails},
+		LogErrors:           cfg.LogErrors,
+		ErrorFormat:         cfg.ErrorFormat,
 	}
 }
 
+// requestHeaderRules returns the request header rules of cfg, forwarding the headers bound to
+// request fields as well
+func requestHeaderRules(cfg *Config, bindings HeaderBindings) HeaderRules {
+	rules := cfg.RequestHeaders
+	rules.Allow = append(slices.Clone(rules.Allow), bindings.headers()...)
+	return rules
+}
+
 func main() {
+	// "server apikey ..." manages API keys instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := runAPIKeyCommand(os.Args[2:]); err != nil {
+			fatal("apikey failed", "error", err)
+		}
+		return
+	}
+
+	cfg, err := loadConfig(os.Args[1:])
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+
+	// Structured logs; the level and the redacted keys can be changed on reload.
+	// The standard log package writes through the same handler.
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.LogLevel)
+	logRedactor := NewLogRedactor(cfg.LogRedact)
+	slog.SetDefault(slog.New(newLogHandler(os.Stderr, cfg.LogFormat, logLevel, logRedactor)))
+	if cfg.ConfigFile != "" {
+		slog.Info("Loaded configuration", "file", cfg.ConfigFile)
+	}
+
+	// Trace requests through the gateway, the service and the store
+	shutdownTracing, err := setupTracing(context.Background(), cfg)
+	if err != nil {
+		fatal("Failed to set up tracing", "error", err)
+	}
+	if cfg.TraceExporter != TraceExporterNone {
+		slog.Info("Exporting traces", "exporter", cfg.TraceExporter, "sample_ratio", cfg.TraceSampleRatio)
+	}
+
+	// Access log of the HTTP gateway
+	accessLog, closeAccessLog, err := openAccessLog(cfg)
+	if err != nil {
+		fatal("Failed to open access log", "error", err)
+	}
+	if cfg.AccessLogFormat != AccessLogOff && cfg.AccessLogFile != "" {
+		slog.Info("Writing access log", "format", cfg.AccessLogFormat, "file", cfg.AccessLogFile)
+	}
+
+	// Create custom error handler
+	errorHandler := NewReloadableErrorHandler(newErrorHandler(cfg))
+
+	// Prometheus metrics; the wrapped error handler counts error responses and panics
+	metrics := NewMetrics()
+	countedErrors := metrics.ErrorHandler(errorHandler)
+
+	// Request fields bound to headers with the (bind) option of discover.proto
+	services := discoverservicepb.File_pb_discover_proto.Services()
+	bindings := loadHeaderBindings(services.ByName("DiscoverService"))
+	// PATCH bodies are checked before the gateway infers their update mask
+	patchBodies := loadPatchBodies(services.ByName("DiscoverService"))
+
 	// Create a new HTTP server mux with custom options
+	requestHeaders := NewReloadableHeaderMatcher(IncomingHeaderMatcher(requestHeaderRules(cfg, bindings)))
+	responseHeaders := NewReloadableHeaderMatcher(OutgoingHeaderMatcher(cfg.ResponseHeaders))
 	mux := runtime.NewServeMux(
-		runtime.WithIncomingHeaderMatcher(customHeaderMatcher),
+		runtime.WithIncomingHeaderMatcher(requestHeaders.Match),
+		runtime.WithOutgoingHeaderMatcher(responseHeaders.Match),
+		runtime.WithErrorHandler(GatewayErrorHandler(countedErrors, responseHeaders.Match)),
+		runtime.WithMiddlewares(RouteMiddleware, metrics.InFlightMiddleware, ReadMaskMiddleware, PatchBodyMiddleware(countedErrors, patchBodies)),
+		runtime.WithForwardResponseRewriter(SparseResponseRewriter),
+		runtime.WithMetadata(GatewayClientIPMetadata),
 	)
 
+	// Open the storage backend
+	store, err := openStore(cfg)
+	if err != nil {
+		fatal("Failed to open store", "store", cfg.StoreKind, "error", err)
+	}
+	defer store.Close()
+	store = newTracedStore(store, cfg.StoreKind)
+
+	pageTokens, err := newPageTokenCodec(cfg.PageTokenSecret)
+	if err != nil {
+		fatal("Failed to create page token codec", "error", err)
+	}
+
 	// Create the service implementation
-	discoverService := &server{}
+	discoverService := &server{store: store, pageTokens: pageTokens}
 
-	// Register the HTTP handlers directly with the server implementation
+	// Context bounding the gateway registrations (and the endpoint connection)
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// Register the HTTP handlers using the direct server registration
-	if err := discoverservicepb.RegisterDiscoverServiceHandlerServer(ctx, mux, discoverService); err != nil {
-		log.Fatalf("Failed to register HTTP handlers: %v", err)
+	// Interceptors shared by native gRPC calls and in-process gateway calls
+	interceptors := []grpc.UnaryServerInterceptor{
+		UnaryRequestIDInterceptor(),
+		UnaryTracingInterceptor(),
+		UnaryLoggingInterceptor(),
+		metrics.UnaryInterceptor(),
+	}
+	authenticator := &Authenticator{TenantClaim: cfg.TenantClaim}
+	if authenticator.JWT, err = newJWTVerifier(cfg); err != nil {
+		fatal("Failed to load JWT keys", "error", err)
+	}
+	if cfg.APIKeysFile != "" {
+		if authenticator.APIKeys, err = OpenAPIKeyStore(cfg.APIKeysFile); err != nil {
+			fatal("Failed to load API keys", "file", cfg.APIKeysFile, "error", err)
+		}
+	}
+	tenants := &TenantResolver{Claim: cfg.TenantClaim, Required: cfg.RequireTenant}
+	trustedProxies, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		fatal("Invalid trusted proxies", "error", err)
+	}
+	rateLimiter := NewRateLimiter(cfg.RateLimits)
+	for _, key := range rateLimitKeys {
+		if limit, ok := cfg.RateLimits[key]; ok {
+			slog.Info("Rate limit", "per", key, "limit", limit.String())
+		}
+	}
+	// Access rules are declared with the (authorization) option of each RPC in discover.proto
+	policy := loadAuthorizationPolicy(services.ByName("DiscoverService"))
+	if authenticator.enabled() {
+		slog.Info("Loaded authorization rules", "rpcs", len(policy))
+		interceptors = append(interceptors,
+			UnaryRateLimitInterceptor(rateLimiter, trustedProxies, anonymousRateLimitKeys...),
+			UnaryAuthInterceptor(authenticator),
+			UnaryTenantInterceptor(tenants),
+			UnaryRateLimitInterceptor(rateLimiter, trustedProxies, authenticatedRateLimitKeys...),
+			UnaryAuthorizationInterceptor(policy),
+		)
+	} else {
+		// Fail closed: the (authorization) rules cannot be enforced without authentication
+		if len(policy) > 0 && !cfg.InsecureDisableAuth {
+			fatal("Refusing to start without authentication: configure JWT keys (-jwt-hs256-secret, -jwt-public-key, -jwt-jwks) or -api-keys-file, or set -insecure-disable-auth", "rpcs_with_authorization_rules", len(policy))
+		}
+		slog.Warn("Authentication and authorization disabled by -insecure-disable-auth: every RPC is open to anyone")
+		interceptors = append(interceptors, UnaryTenantInterceptor(tenants), UnaryRateLimitInterceptor(rateLimiter, trustedProxies, rateLimitKeys...))
+	}
+	interceptors = append(interceptors, UnaryHeaderBindingInterceptor(bindings))
+
+	// Create the native gRPC server
+	grpcServer := newGRPCServer(discoverService, interceptors...)
+
+	// Register the HTTP handlers, either in-process or through the gRPC server
+	if err := registerGateway(ctx, mux, cfg, discoverService, interceptors...); err != nil {
+		fatal("Failed to register HTTP handlers", "error", err)
 	}
 
-	// Create custom error handler
-	errorHandler := &CustomErrorHandler{
-		LogErrors: true, // Enable error logging
+	// Retried POSTs carrying an Idempotency-Key replay the original response
+	idempotencyKeys := NewIdempotencyCache(cfg.IdempotencyWindow, cfg.IdempotencyMaxEntries, cfg.IdempotencyMaxBytes)
+	resolveTenant := func(r *http.Request) (string, error) {
+		return tenants.resolveRequest(r, authenticator)
 	}
+	idempotent := IdempotencyMiddleware(countedErrors, idempotencyKeys, resolveTenant, "/v1/post/unstructured-data")
 
-	// Create HTTP server with error handling middleware
+	// Create HTTP server with request ID, client IP, tracing, metrics, logging, access log and error handling middleware
+	gatewayHandler := RequestIDMiddleware(ClientIPMiddleware(trustedProxies)(TracingMiddleware(metrics.Middleware(LoggingMiddleware(accessLog.Middleware(ErrorHandlingMiddleware(countedErrors)(ConditionalRequestMiddleware(idempotent(mux)))))))))
 	httpServer := &http.Server{
-		Addr:    ":8080",
-		Handler: ErrorHandlingMiddleware(errorHandler)(mux),
+		Addr:    cfg.HTTPAddr,
+		Handler: gatewayHandler,
+	}
+	swaggerURL := "http://localhost" + cfg.SwaggerAddr + "/swagger-ui/"
+
+	if cfg.SinglePort {
+		// gRPC, the REST gateway and the Swagger UI share the HTTP port
+		rootMux := http.NewServeMux()
+		rootMux.Handle("/swagger-ui/", swaggerHandler(cfg.SwaggerFile))
+		rootMux.Handle("/", gatewayHandler)
+
+		httpServer.Handler = multiplexHandler(grpcServer, rootMux)
+		httpServer.Protocols = singlePortProtocols()
+		swaggerURL = "http://localhost" + cfg.HTTPAddr + "/swagger-ui/"
+	} else {
+		grpcListener, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			fatal("Failed to listen", "addr", cfg.GRPCAddr, "error", err)
+		}
+
+		// Start gRPC server in a goroutine
+		go func() {
+			slog.Info("Starting gRPC server", "addr", grpcListener.Addr().String())
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				fatal("Failed to serve gRPC", "error", err)
+			}
+		}()
+
+		// Serve Swagger UI
+		go SwaggerUI(cfg.SwaggerAddr, cfg.SwaggerFile)
+	}
+
+	// Serve the metrics on the admin port
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:    cfg.MetricsAddr,
+			Handler: metricsMux,
+		}
+		go func() {
+			slog.Info("Starting metrics server", "addr", metricsServer.Addr, "path", "/metrics")
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("Failed to serve metrics", "error", err)
+			}
+		}()
 	}
 
 	// Start HTTP server in a goroutine
 	go func() {
-		log.Printf("Starting HTTP server on %s", httpServer.Addr)
-		log.Printf("API endpoints:")
-		log.Printf("  GET  /v1/get-param-in-body/{id}")
-		log.Printf("  GET  /v1/get-param-in-header")
-		log.Printf("  POST /v1/post/unstructured-data")
-		log.Printf("  Swagger UI: http://localhost:8081/swagger-ui/")
-		log.Printf("")
-		log.Printf("Error handling examples:")
-		log.Printf("  GET  /v1/get-param-in-body/not-found     -> 404 Not Found")
-		log.Printf("  GET  /v1/get-param-in-body/unauthorized  -> 403 Forbidden")
-		log.Printf("  GET  /v1/get-param-in-body/error         -> 500 Internal Server Error")
-		log.Printf("  GET  /v1/get-param-in-header (no header) -> 400 Bad Request")
-		log.Printf("  POST /v1/post/unstructured-data (duplicate id) -> 409 Conflict")
+		if cfg.SinglePort {
+			slog.Info("Starting gRPC + HTTP server", "addr", httpServer.Addr, "gateway_mode", cfg.GatewayMode)
+		} else {
+			slog.Info("Starting HTTP server", "addr", httpServer.Addr, "gateway_mode", cfg.GatewayMode)
+		}
+		slog.Info("API endpoints", "swagger_ui", swaggerURL, "endpoints", []string{
+			"GET    /v1/get-param-in-body/{id}",
+			"GET    /v1/get-param-in-header",
+			"POST   /v1/post/unstructured-data",
+			"GET    /v1/unstructured-data",
+			"GET    /v1/unstructured-data/{id}",
+			"PATCH  /v1/unstructured-data/{id}",
+			"DELETE /v1/unstructured-data/{id}",
+		})
+		slog.Info("Error handling examples", "examples", []string{
+			"GET /v1/get-param-in-body/not-found -> 404 Not Found",
+			"GET /v1/get-param-in-body/error -> 500 Internal Server Error",
+			"GET /v1/get-param-in-header (no header) -> 400 Bad Request",
+			"POST /v1/post/unstructured-data (duplicate id) -> 409 Conflict",
+			"DELETE /v1/unstructured-data/{id} (JWT without admin role) -> 403 Forbidden",
+			"POST /v1/post/unstructured-data (reused Idempotency-Key, other body) -> 422 Unprocessable Entity",
+			"any request over a -rate-limit-* limit -> 429 Too Many Requests",
+		})
 
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to serve HTTP: %v", err)
+			fatal("Failed to serve HTTP", "error", err)
 		}
 	}()
 
-	// Serve Swagger UI
-	go SwaggerUI()
+	// Reload the configuration on SIGHUP and when its files change
+	reloader := &Reloader{
+		args:            os.Args[1:],
+		logLevel:        logLevel,
+		logRedactor:     logRedactor,
+		errorHandler:    errorHandler,
+		requestHeaders:  requestHeaders,
+		responseHeaders: responseHeaders,
+		bindings:        bindings,
+		rateLimiter:     rateLimiter,
+		authenticator:   authenticator,
+		current:         cfg,
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloader.reload("SIGHUP")
+		}
+	}()
+	if cfg.WatchConfig {
+		var watched []string
+		for _, path := range []string{cfg.ConfigFile, cfg.APIKeysFile, cfg.JWTPublicKeyFile, cfg.JWTJWKSFile} {
+			if path != "" {
+				watched = append(watched, path)
+			}
+		}
+		if len(watched) > 0 {
+			if err := watchFiles(ctx, reloader.reload, watched...); err != nil {
+				slog.Warn("Not watching configuration files", "error", err)
+			} else {
+				slog.Info("Watching configuration files for changes", "files", watched)
+			}
+		}
+	}
 
 	// Wait for interrupt signal to gracefully shutdown the servers
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down servers...")
+	slog.Info("Shutting down servers")
 
 	// Graceful shutdown
-	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel = context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := httpServer.Shutdown(ctx); err != nil {
-		log.Printf("HTTP server shutdown error: %v", err)
+		slog.Error("HTTP server shutdown failed", "error", err)
+	}
+
+	// Stop the gRPC server after the gateway so in-flight gateway calls can complete
+	stopGRPCServer(ctx, grpcServer)
+
+	// Keep the metrics available until the requests are done
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			slog.Error("Metrics server shutdown failed", "error", err)
+		}
+	}
+
+	if err := closeAccessLog(); err != nil {
+		slog.Error("Access log close failed", "error", err)
+	}
+
+	// Export the spans of the last requests
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Tracing shutdown failed", "error", err)
 	}
 
-	log.Println("Servers stopped gracefully")
+	slog.Info("Servers stopped gracefully")
 }
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// unmatchedRoute labels HTTP requests that matched no gateway route, keeping raw paths out of the labels
+const unmatchedRoute = "unmatched"
+
+// Metrics collects the Prometheus metrics of the HTTP gateway and the gRPC service
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	httpInFlight *prometheus.GaugeVec
+
+	rpcs        *prometheus.CounterVec
+	rpcDuration *prometheus.HistogramVec
+	rpcInFlight *prometheus.GaugeVec
+
+	errors *prometheus.CounterVec
+	panics prometheus.Counter
+}
+
+// NewMetrics creates the metrics in a registry of their own, along with the Go runtime and process metrics
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests handled, by method, route pattern and status code.",
+		}, []string{"method", "route", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duration of HTTP requests, by method, route pattern and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "code"}),
+		httpInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests being handled by a gateway route, by method and route pattern.",
+		}, []string{"method", "route"}),
+		rpcs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "RPCs completed, by gRPC method and status code, including those of the in-process gateway.",
+		}, []string{"grpc_method", "grpc_code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Duration of RPCs, by gRPC method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"grpc_method", "grpc_code"}),
+		rpcInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "grpc_server_in_flight",
+			Help: "RPCs being handled, by gRPC method.",
+		}, []string{"grpc_method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "error_responses_total",
+			Help: "Error responses written by the ErrorHandler, by ErrorResponse error type.",
+		}, []string{"error"}),
+		panics: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "panics_recovered_total",
+			Help: "Panics recovered by ErrorHandlingMiddleware.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.httpInFlight,
+		m.rpcs, m.rpcDuration, m.rpcInFlight,
+		m.errors, m.panics,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware counts and times HTTP requests by the route pattern the gateway matched.
+// Register RouteMiddleware and InFlightMiddleware on the gateway mux so that routes are known.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, route := withRequestRoute(r.Context())
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		defer func() {
+			pattern := route.pattern
+			if pattern == "" {
+				pattern = unmatchedRoute
+			}
+			code := strconv.Itoa(recorder.status())
+			m.httpRequests.WithLabelValues(r.Method, pattern, code).Inc()
+			m.httpDuration.WithLabelValues(r.Method, pattern, code).Observe(time.Since(start).Seconds())
+		}()
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+	})
+}
+
+// InFlightMiddleware tracks the requests being handled by each gateway route.
+// Register it with runtime.WithMiddlewares.
+func (m *Metrics) InFlightMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		pattern, ok := routePattern(r)
+		if !ok {
+			pattern = unmatchedRoute
+		}
+		inFlight := m.httpInFlight.WithLabelValues(r.Method, pattern)
+		inFlight.Inc()
+		defer inFlight.Dec()
+		next(w, r, pathParams)
+	}
+}
+
+// UnaryInterceptor counts, times and tracks the calls in flight by gRPC method and status code
+func (m *Metrics) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		inFlight := m.rpcInFlight.WithLabelValues(info.FullMethod)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err).String()
+		m.rpcs.WithLabelValues(info.FullMethod, code).Inc()
+		m.rpcDuration.WithLabelValues(info.FullMethod, code).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// ErrorHandler wraps an ErrorHandler to count the error responses by type and the recovered panics
+func (m *Metrics) ErrorHandler(next ErrorHandler) ErrorHandler {
+	return &metricsErrorHandler{next: next, metrics: m}
+}
+
+// metricsErrorHandler counts the errors handled by the wrapped ErrorHandler
+type metricsErrorHandler struct {
+	next    ErrorHandler
+	metrics *Metrics
+}
+
+// HandleError implements ErrorHandler
+func (h *metricsErrorHandler) HandleError(ctx context.Context, err error, req *http.Request) *ErrorResponse {
+	if _, ok := err.(*PanicError); ok {
+		h.metrics.panics.Inc()
+	}
+	response := h.next.HandleError(ctx, err, req)
+	h.metrics.errors.WithLabelValues(response.Error).Inc()
+	return response
+}
+
+// DefaultErrorFormat implements ErrorFormatter
+func (h *metricsErrorHandler) DefaultErrorFormat() ErrorFormat {
+	if formatter, ok := h.next.(ErrorFormatter); ok {
+		return formatter.DefaultErrorFormat()
+	}
+	return ""
+}
+
+// statusRecorder captures the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+// WriteHeader captures the status code
+func (w *statusRecorder) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write captures the implicit 200 status of responses written without WriteHeader
+func (w *statusRecorder) Write(data []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// status returns the status code written, 200 when nothing was written
+func (w *statusRecorder) status() int {
+	if w.statusCode == 0 {
+		return http.StatusOK
+	}
+	return w.statusCode
+}
@@ -4,21 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"sync/atomic"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/code"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 // ErrorResponse represents a standardized error response
 type ErrorResponse struct {
-	Error   string            `json:"error"`
-	Code    int               `json:"code"`
-	Message string            `json:"message"`
-	Details map[string]string `json:"details,omitempty"`
+	Error    string            `json:"error"`
+	Code     int               `json:"code"`
+	Message  string            `json:"message"`
+	Details  []ErrorDetail     `json:"details,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // ErrorHandler defines the interface for custom error handling logic
@@ -27,10 +34,41 @@ type ErrorHandler interface {
 }
 
 // DefaultErrorHandler provides default error handling logic
-type DefaultErrorHandler struct{}
+type DefaultErrorHandler struct {
+	// OmitPanicDetails leaves the panic message and stack trace out of responses
+	OmitPanicDetails bool
+}
 
 // HandleError implements the default error handling logic
 func (h *DefaultErrorHandler) HandleError(ctx context.Context, err error, req *http.Request) *ErrorResponse {
+	response := h.errorResponse(err, req)
+
+	// Every error carries the request ID, so that clients can quote it in support requests
+	requestID := requestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = req.Header.Get(requestIDHeader)
+	}
+	if requestID != "" {
+		if response.Metadata == nil {
+			response.Metadata = make(map[string]string)
+		}
+		response.Metadata["request_id"] = requestID
+	}
+
+	// Record the error on the span of the request; as for HTTP spans, only server errors fail it
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err, trace.WithAttributes(
+		attribute.String("error.type", response.Error),
+		attribute.Int("http.response.status_code", response.Code),
+	))
+	if response.Code >= http.StatusInternalServerError {
+		span.SetStatus(otelcodes.Error, response.Message)
+	}
+	return response
+}
+
+// errorResponse converts an error into its response
+func (h *DefaultErrorHandler) errorResponse(err error, req *http.Request) *ErrorResponse {
 	// Convert gRPC status to HTTP status
 	grpcStatus, ok := status.FromError(err)
 	if ok {
@@ -52,7 +90,7 @@ func (h *DefaultErrorHandler) HandleError(ctx context.Context, err error, req *h
 		Error:   "Internal Server Error",
 		Code:    http.StatusInternalServerError,
 		Message: "An unexpected error occurred",
-		Details: map[string]string{
+		Metadata: map[string]string{
 			"request_path": req.URL.Path,
 			"method":       req.Method,
 		},
@@ -63,38 +101,32 @@ func (h *DefaultErrorHandler) HandleError(ctx context.Context, err error, req *h
 func (h *DefaultErrorHandler) handleGRPCStatus(grpcStatus *status.Status, req *http.Request) *ErrorResponse {
 	httpStatus := grpcStatusToHTTPStatus(grpcStatus.Code())
 
-	response := &ErrorResponse{
-		Error:   grpcStatus.Code().String(),
+	return &ErrorResponse{
+		Error:   code.Code(grpcStatus.Code()).String(),
 		Code:    httpStatus,
 		Message: grpcStatus.Message(),
-		Details: map[string]string{
+		Details: renderErrorDetails(grpcStatus),
+		Metadata: map[string]string{
 			"request_path": req.URL.Path,
 			"method":       req.Method,
 		},
 	}
-
-	// Add additional details based on error code
-	switch grpcStatus.Code() {
-	case codes.InvalidArgument:
-		response.Message = "Invalid request parameters"
-	case codes.NotFound:
-		response.Message = "Resource not found"
-	case codes.PermissionDenied:
-		response.Message = "Access denied"
-	case codes.Unauthenticated:
-		response.Message = "Authentication required"
-	}
-
-	return response
 }
 
 // handleRuntimeError handles runtime.HTTPStatusError
 func (h *DefaultErrorHandler) handleRuntimeError(err *runtime.HTTPStatusError, req *http.Request) *ErrorResponse {
+	// Routing errors wrap a gRPC status; keep its code but use the HTTP status chosen by the gateway
+	if grpcStatus, ok := status.FromError(err.Err); ok {
+		response := h.handleGRPCStatus(grpcStatus, req)
+		response.Code = err.HTTPStatus
+		return response
+	}
+
 	return &ErrorResponse{
 		Error:   "HTTP Status Error",
 		Code:    err.HTTPStatus,
 		Message: err.Err.Error(),
-		Details: map[string]string{
+		Metadata: map[string]string{
 			"request_path": req.URL.Path,
 			"method":       req.Method,
 		},
@@ -103,17 +135,20 @@ func (h *DefaultErrorHandler) handleRuntimeError(err *runtime.HTTPStatusError, r
 
 // handlePanicError handles panic errors
 func (h *DefaultErrorHandler) handlePanicError(err *PanicError, req *http.Request) *ErrorResponse {
-	return &ErrorResponse{
+	response := &ErrorResponse{
 		Error:   "Panic Error",
 		Code:    http.StatusInternalServerError,
 		Message: "A panic occurred while processing the request",
-		Details: map[string]string{
+		Metadata: map[string]string{
 			"request_path": req.URL.Path,
 			"method":       req.Method,
-			"panic_msg":    err.Error(),
-			"stack_trace":  err.StackTrace,
 		},
 	}
+	if !h.OmitPanicDetails {
+		response.Metadata["panic_msg"] = err.Error()
+		response.Metadata["stack_trace"] = err.StackTrace
+	}
+	return response
 }
 
 // PanicError represents a panic error with stack trace
@@ -172,12 +207,14 @@ func grpcStatusToHTTPStatus(code codes.Code) int {
 func ErrorHandlingMiddleware(errorHandler ErrorHandler) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Create a custom response writer that captures errors
-			errorWriter := &ErrorResponseWriter{
-				ResponseWriter: w,
-				ErrorHandler:   errorHandler,
-				Request:        r,
+			// Create a custom response writer that captures errors, or reuse the one of
+			// AccessLogger.Middleware so that it sees the status of panic responses
+			errorWriter, ok := w.(*ErrorResponseWriter)
+			if !ok {
+				errorWriter = &ErrorResponseWriter{ResponseWriter: w}
 			}
+			errorWriter.ErrorHandler = errorHandler
+			errorWriter.Request = r
 
 			// Recover from panics
 			defer func() {
@@ -188,10 +225,10 @@ func ErrorHandlingMiddleware(errorHandler ErrorHandler) func(http.Handler) http.
 						StackTrace: stackTrace,
 					}
 
-					log.Printf("Panic recovered: %v\n%s", rec, stackTrace)
+					LoggerFromContext(r.Context()).Error("Panic recovered", "panic", rec, "stack", stackTrace)
 
 					response := errorHandler.HandleError(r.Context(), panicErr, r)
-					writeErrorResponse(w, response)
+					writeErrorResponse(errorWriter, response, errorFormatFor(errorHandler, r), r)
 				}
 			}()
 
@@ -200,18 +237,60 @@ func ErrorHandlingMiddleware(errorHandler ErrorHandler) func(http.Handler) http.
 	}
 }
 
+// GatewayErrorHandler adapts an ErrorHandler to the grpc-gateway error handler signature.
+// Register it with runtime.WithErrorHandler so that errors returned by the generated
+// handlers (service errors, body decode and path parameter errors, marshaling failures
+// and routing errors) are rendered as ErrorResponse instead of the gateway's default body.
+// Response metadata is written as headers with outgoingHeaders, the mux's outgoing header matcher.
+func GatewayErrorHandler(errorHandler ErrorHandler, outgoingHeaders runtime.HeaderMatcherFunc) runtime.ErrorHandlerFunc {
+	return func(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+		// The handler may have announced trailers before failing
+		w.Header().Del("Trailer")
+		w.Header().Del("Transfer-Encoding")
+
+		// Forward response metadata set by the handler before it failed, named as on success
+		if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+			for key, values := range md.HeaderMD {
+				name, ok := outgoingHeaders(key)
+				if !ok {
+					continue
+				}
+				for _, value := range values {
+					w.Header().Add(name, value)
+				}
+			}
+		}
+
+		// Tell the client which authentication scheme is expected (RFC 6750)
+		if status.Code(err) == codes.Unauthenticated {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+errorDomain+`"`)
+		}
+
+		// Tell the client when to retry, in whole seconds (RFC 9110)
+		if delay, ok := retryDelay(err); ok {
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(delay.Seconds())), 10))
+		}
+
+		response := errorHandler.HandleError(ctx, err, r)
+		writeErrorResponse(w, response, errorFormatFor(errorHandler, r), r)
+	}
+}
+
 // ErrorResponseWriter wraps http.ResponseWriter to capture errors
 type ErrorResponseWriter struct {
 	http.ResponseWriter
 	ErrorHandler ErrorHandler
 	Request      *http.Request
 	statusCode   int
+	bytes        int64
 	written      bool
 }
 
 // WriteHeader captures the status code
 func (w *ErrorResponseWriter) WriteHeader(statusCode int) {
-	w.statusCode = statusCode
+	if !w.written {
+		w.statusCode = statusCode
+	}
 	w.ResponseWriter.WriteHeader(statusCode)
 	w.written = true
 }
@@ -219,22 +298,49 @@ func (w *ErrorResponseWriter) WriteHeader(statusCode int) {
 // Write captures the response
 func (w *ErrorResponseWriter) Write(data []byte) (int, error) {
 	w.written = true
-	return w.ResponseWriter.Write(data)
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += int64(n)
+	return n, err
 }
 
-// writeErrorResponse writes an error response in JSON format
-func writeErrorResponse(w http.ResponseWriter, response *ErrorResponse) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(response.Code)
+// Unwrap returns the wrapped writer for http.ResponseController
+func (w *ErrorResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
 
-	jsonData, err := json.Marshal(response)
+// StatusCode returns the status code of the response, 200 when nothing was written
+func (w *ErrorResponseWriter) StatusCode() int {
+	if w.statusCode == 0 {
+		return http.StatusOK
+	}
+	return w.statusCode
+}
+
+// BytesWritten returns the size of the response body written
+func (w *ErrorResponseWriter) BytesWritten() int64 {
+	return w.bytes
+}
+
+// writeErrorResponse writes an error response in the requested format:
+// ErrorResponse JSON or RFC 9457 Problem Details
+func writeErrorResponse(w http.ResponseWriter, response *ErrorResponse, format ErrorFormat, req *http.Request) {
+	var body any = response
+	contentType := jsonContentType
+	if format == ErrorFormatProblem {
+		body = newProblemDetails(response, req)
+		contentType = problemContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+
+	jsonData, err := json.Marshal(body)
 	if err != nil {
 		// Fallback to simple error response
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"error":"Internal Server Error","code":500,"message":"Failed to serialize error response"}`))
+		w.Write([]byte(`{"error":"INTERNAL","code":500,"message":"Failed to serialize error response"}`))
 		return
 	}
 
+	w.WriteHeader(response.Code)
 	w.Write(jsonData)
 }
 
@@ -243,13 +349,28 @@ type CustomErrorHandler struct {
 	DefaultErrorHandler
 	// Add custom fields as needed
 	LogErrors bool
+	// ErrorFormat is used when the request Accept header does not select a format
+	ErrorFormat ErrorFormat
+}
+
+// DefaultErrorFormat implements ErrorFormatter
+func (h *CustomErrorHandler) DefaultErrorFormat() ErrorFormat {
+	return h.ErrorFormat
 }
 
 // HandleError implements custom error handling logic
 func (h *CustomErrorHandler) HandleError(ctx context.Context, err error, req *http.Request) *ErrorResponse {
 	// Log errors if enabled
 	if h.LogErrors {
-		log.Printf("Error handling request %s %s: %v", req.Method, req.URL.Path, err)
+		// The tenant of calls through a gRPC hop is only known from the error
+		if tenant := errorTenant(err); tenant != "" {
+			setLogAttr(ctx, "tenant", tenant)
+		}
+		logger := LoggerFromContext(ctx)
+		if _, ok := ctx.Value(requestLogKey{}).(*requestLog); !ok {
+			logger = logger.With("http_method", req.Method, "path", req.URL.Path)
+		}
+		logger.Warn("Error handling request", "error", err)
 	}
 
 	// Call the default handler first
@@ -257,18 +378,37 @@ func (h *CustomErrorHandler) HandleError(ctx context.Context, err error, req *ht
 
 	// Add custom logic here
 	// For example, you could:
-	// - Add request ID to error details
 	// - Send errors to external monitoring services
 	// - Add custom error codes
 	// - Modify error messages based on environment
 
-	// Example: Add request ID if available
-	if requestID := req.Header.Get("X-Request-ID"); requestID != "" {
-		if response.Details == nil {
-			response.Details = make(map[string]string)
-		}
-		response.Details["request_id"] = requestID
-	}
-
 	return response
 }
+
+// ReloadableErrorHandler delegates to a CustomErrorHandler that can be replaced while serving,
+// so that error logging and verbosity can change without a restart
+type ReloadableErrorHandler struct {
+	current atomic.Pointer[CustomErrorHandler]
+}
+
+// NewReloadableErrorHandler creates a handler delegating to h
+func NewReloadableErrorHandler(h *CustomErrorHandler) *ReloadableErrorHandler {
+	r := &ReloadableErrorHandler{}
+	r.Store(h)
+	return r
+}
+
+// Store replaces the handler used for the following errors
+func (r *ReloadableErrorHandler) Store(h *CustomErrorHandler) {
+	r.current.Store(h)
+}
+
+// HandleError implements ErrorHandler
+func (r *ReloadableErrorHandler) HandleError(ctx context.Context, err error, req *http.Request) *ErrorResponse {
+	return r.current.Load().HandleError(ctx, err, req)
+}
+
+// DefaultErrorFormat implements ErrorFormatter
+func (r *ReloadableErrorHandler) DefaultErrorFormat() ErrorFormat {
+	return r.current.Load().DefaultErrorFormat()
+}
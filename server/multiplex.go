@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// grpcContentType is the content type prefix of gRPC requests
+const grpcContentType = "application/grpc"
+
+// isGRPCRequest reports whether the request is a gRPC call: HTTP/2 with an application/grpc content type
+func isGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), grpcContentType)
+}
+
+// multiplexHandler routes gRPC calls to the gRPC server and everything else to the HTTP handler,
+// so that gRPC, the REST gateway and the Swagger UI can share one port
+func multiplexHandler(grpcServer *grpc.Server, httpHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isGRPCRequest(r) {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		httpHandler.ServeHTTP(w, r)
+	})
+}
+
+// singlePortProtocols accepts HTTP/1.1 and cleartext HTTP/2 with prior knowledge (h2c),
+// which is how gRPC clients connect without TLS
+func singlePortProtocols() *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// patchBody is the request field filled with the JSON body of a PATCH route
+type patchBody struct {
+	// prefix is the name of the body field in field violations, empty when the body is the whole request
+	prefix  string
+	message protoreflect.MessageDescriptor
+}
+
+// PatchBodies holds the bodies of the PATCH routes declared with (google.api.http), keyed by route pattern
+type PatchBodies map[string]patchBody
+
+// loadPatchBodies reads the PATCH rules of the (google.api.http) options of the services
+func loadPatchBodies(services ...protoreflect.ServiceDescriptor) PatchBodies {
+	bodies := PatchBodies{}
+	for _, service := range services {
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			rule, _ := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+			if rule.GetPatch() == "" || rule.GetBody() == "" {
+				continue
+			}
+			if rule.GetBody() == "*" {
+				bodies[rule.GetPatch()] = patchBody{message: method.Input()}
+				continue
+			}
+			field := method.Input().Fields().ByName(protoreflect.Name(rule.GetBody()))
+			if field == nil || field.Message() == nil || field.IsList() || field.IsMap() {
+				continue
+			}
+			bodies[rule.GetPatch()] = patchBody{prefix: string(field.Name()) + ".", message: field.Message()}
+		}
+	}
+	return bodies
+}
+
+// nullFields reports the message fields set to null in the JSON body. Bodies that are not
+// JSON objects are left to the gateway's decoder to reject.
+func (b patchBody) nullFields(body []byte) []*errdetails.BadRequest_FieldViolation {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
+	}
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	for key, value := range fields {
+		fd := b.message.Fields().ByName(protoreflect.Name(key))
+		if fd == nil {
+			fd = b.message.Fields().ByJSONName(key)
+		}
+		if fd == nil || fd.Message() == nil || fd.IsList() || fd.IsMap() || string(bytes.TrimSpace(value)) != "null" {
+			continue
+		}
+		violations = append(violations, fieldViolation(b.prefix+string(fd.Name()),
+			string(fd.Name())+" cannot be null; leave it out of the body to keep it unchanged"))
+	}
+	return violations
+}
+
+// PatchBodyMiddleware rejects PATCH bodies setting message fields to null with InvalidArgument.
+// Without an update_mask the gateway infers one from the body, which panics on null messages.
+// Register it with runtime.WithMiddlewares.
+func PatchBodyMiddleware(errorHandler ErrorHandler, bodies PatchBodies) runtime.Middleware {
+	return func(next runtime.HandlerFunc) runtime.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			pattern, _ := routePattern(r)
+			body, ok := bodies[pattern]
+			if r.Method != http.MethodPatch || !ok {
+				next(w, r, pathParams)
+				return
+			}
+
+			writeError := func(err error) {
+				response := errorHandler.HandleError(r.Context(), err, r)
+				writeErrorResponse(w, response, errorFormatFor(errorHandler, r), r)
+			}
+
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(invalidArgumentError(fieldViolation("body", "failed to read request body")))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(data))
+
+			if violations := body.nullFields(data); len(violations) > 0 {
+				writeError(invalidArgumentError(violations...))
+				return
+			}
+			next(w, r, pathParams)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrorFormat selects how error responses are rendered
+type ErrorFormat string
+
+const (
+	// ErrorFormatJSON renders errors as ErrorResponse
+	ErrorFormatJSON ErrorFormat = "json"
+	// ErrorFormatProblem renders errors as RFC 9457 Problem Details
+	ErrorFormatProblem ErrorFormat = "problem"
+)
+
+const (
+	jsonContentType    = "application/json"
+	problemContentType = "application/problem+json"
+)
+
+// parseErrorFormat parses an error format name
+func parseErrorFormat(name string) (ErrorFormat, error) {
+	switch format := ErrorFormat(strings.ToLower(name)); format {
+	case ErrorFormatJSON, ErrorFormatProblem:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown error format %q (want %q or %q)", name, ErrorFormatJSON, ErrorFormatProblem)
+	}
+}
+
+// ErrorFormatter is implemented by error handlers that choose the error format
+// used when the client does not ask for one
+type ErrorFormatter interface {
+	DefaultErrorFormat() ErrorFormat
+}
+
+// ProblemDetails is an RFC 9457 (formerly RFC 7807) problem details object.
+// The error, details and metadata extension members carry the same data as ErrorResponse.
+type ProblemDetails struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Details  []ErrorDetail     `json:"details,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// newProblemDetails converts an ErrorResponse into problem details for the request
+func newProblemDetails(response *ErrorResponse, req *http.Request) *ProblemDetails {
+	problem := &ProblemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(response.Code),
+		Status:   response.Code,
+		Detail:   response.Message,
+		Error:    response.Error,
+		Details:  response.Details,
+		Metadata: response.Metadata,
+	}
+	if response.Error != "" {
+		// One problem type per canonical error code, e.g. urn:discoverservice:problem:not-found
+		problem.Type = "urn:" + errorDomain + ":problem:" + strings.Join(strings.FieldsFunc(strings.ToLower(response.Error), func(r rune) bool {
+			return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+		}), "-")
+	}
+	if req != nil {
+		problem.Instance = req.URL.Path
+	}
+	return problem
+}
+
+// negotiateErrorFormat picks the error format from the request Accept header.
+// application/problem+json selects Problem Details and application/json selects ErrorResponse;
+// when neither is preferred the fallback format is used.
+func negotiateErrorFormat(req *http.Request, fallback ErrorFormat) ErrorFormat {
+	if req == nil {
+		return fallback
+	}
+
+	problemQ, jsonQ := -1.0, -1.0
+	for _, accept := range req.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if value, ok := params["q"]; ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+			switch mediaType {
+			case problemContentType:
+				problemQ = max(problemQ, q)
+			case jsonContentType:
+				jsonQ = max(jsonQ, q)
+			}
+		}
+	}
+
+	switch {
+	case problemQ > 0 && problemQ > jsonQ:
+		return ErrorFormatProblem
+	case jsonQ > 0 && jsonQ > problemQ:
+		return ErrorFormatJSON
+	default:
+		return fallback
+	}
+}
+
+// errorFormatFor returns the format to render an error with for the request
+func errorFormatFor(errorHandler ErrorHandler, req *http.Request) ErrorFormat {
+	fallback := ErrorFormatJSON
+	if formatter, ok := errorHandler.(ErrorFormatter); ok && formatter.DefaultErrorFormat() != "" {
+		fallback = formatter.DefaultErrorFormat()
+	}
+	return negotiateErrorFormat(req, fallback)
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Response metadata keys of the rate limit state (draft-ietf-httpapi-ratelimit-headers).
+// The gateway forwards them as the RateLimit-Limit and RateLimit-Remaining headers.
+const (
+	rateLimitLimitMetadataKey     = "ratelimit-limit"
+	rateLimitRemainingMetadataKey = "ratelimit-remaining"
+)
+
+// RateLimitKey selects what a rate limit counts requests by
+type RateLimitKey string
+
+const (
+	// RateLimitKeyAPIKey limits each API key; callers using bearer tokens are not counted
+	RateLimitKeyAPIKey RateLimitKey = "api_key"
+	// RateLimitKeyTenant limits each tenant
+	RateLimitKeyTenant RateLimitKey = "tenant"
+	// RateLimitKeyIP limits each client IP address
+	RateLimitKeyIP RateLimitKey = "ip"
+	// RateLimitKeyRoute limits each RPC method, across all callers
+	RateLimitKeyRoute RateLimitKey = "route"
+)
+
+// rateLimitKeys lists the keys in the order they are checked
+var rateLimitKeys = []RateLimitKey{RateLimitKeyAPIKey, RateLimitKeyTenant, RateLimitKeyIP, RateLimitKeyRoute}
+
+// Rate limit keys known before and after the caller is authenticated. The anonymous keys are
+// checked first, so that calls with missing or invalid credentials are throttled too.
+var (
+	anonymousRateLimitKeys     = []RateLimitKey{RateLimitKeyIP, RateLimitKeyRoute}
+	authenticatedRateLimitKeys = []RateLimitKey{RateLimitKeyAPIKey, RateLimitKeyTenant}
+)
+
+// RateLimit is a token bucket: Burst requests can be made at once,
+// and the bucket refills at Requests per Period
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// parseRateLimit parses a limit written as <requests>/<period>[:<burst>], e.g. "100/m" or "10/s:50".
+// The period is s, m, h or a duration such as 10s; the burst defaults to the request count.
+func parseRateLimit(value string) (RateLimit, error) {
+	rate, burst, hasBurst := strings.Cut(value, ":")
+	requests, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q (want <requests>/<period>[:<burst>], e.g. 100/m)", value)
+	}
+
+	limit := RateLimit{}
+	var err error
+	if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: request count must be a positive integer", value)
+	}
+	switch period {
+	case "s":
+		limit.Period = time.Second
+	case "m":
+		limit.Period = time.Minute
+	case "h":
+		limit.Period = time.Hour
+	default:
+		if limit.Period, err = time.ParseDuration(period); err != nil || limit.Period <= 0 {
+			return RateLimit{}, fmt.Errorf("invalid rate limit %q: period must be s, m, h or a positive duration", value)
+		}
+	}
+	limit.Burst = limit.Requests
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst <= 0 {
+			return RateLimit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", value)
+		}
+	}
+	return limit, nil
+}
+
+// UnmarshalText reads a limit written as in parseRateLimit, as used in configuration files
+func (l *RateLimit) UnmarshalText(text []byte) error {
+	limit, err := parseRateLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+// String formats the limit the way parseRateLimit reads it
+func (l RateLimit) String() string {
+	return fmt.Sprintf("%d/%s:%d", l.Requests, l.Period, l.Burst)
+}
+
+// tokenBucket is the state of one rate limited key
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter enforces token bucket limits per API key, tenant, client IP or route.
+// Buckets are kept in memory, so each server instance counts on its own.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limits    map[RateLimitKey]RateLimit
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates a limiter enforcing the given limits
+func NewRateLimiter(limits map[RateLimitKey]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limits:  limits,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// SetLimits replaces the limits. Buckets keep their tokens, capped at the new burst.
+func (l *RateLimiter) SetLimits(limits map[RateLimitKey]RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+}
+
+// rateLimitResult is the state of the bucket a request was counted against
+type rateLimitResult struct {
+	subject    string
+	limit      RateLimit
+	remaining  int
+	retryAfter time.Duration
+}
+
+// allow takes a token from the bucket of each limited key of the request.
+// It returns the bucket with the fewest tokens left, and false if that bucket was empty;
+// no tokens are taken when a request is rejected.
+func (l *RateLimiter) allow(keys map[RateLimitKey]string, now time.Time) (*rateLimitResult, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	var tightest *rateLimitResult
+	var taken []*tokenBucket
+	for _, kind := range rateLimitKeys {
+		limit, ok := l.limits[kind]
+		value := keys[kind]
+		if !ok || value == "" {
+			continue
+		}
+
+		subject := string(kind) + ":" + value
+		bucket := l.bucket(subject, limit, now)
+		if bucket.tokens < 1 {
+			// Give back the tokens taken from the other buckets
+			for _, b := range taken {
+				b.tokens++
+			}
+			rate := float64(limit.Requests) / limit.Period.Seconds()
+			retryAfter := time.Duration(math.Ceil((1 - bucket.tokens) / rate * float64(time.Second)))
+			return &rateLimitResult{subject: subject, limit: limit, retryAfter: retryAfter}, false
+		}
+		bucket.tokens--
+		taken = append(taken, bucket)
+
+		if tightest == nil || int(bucket.tokens) < tightest.remaining {
+			tightest = &rateLimitResult{subject: subject, limit: limit, remaining: int(bucket.tokens)}
+		}
+	}
+	return tightest, true
+}
+
+// bucket returns the bucket of the subject, refilled up to now. The caller holds l.mu.
+func (l *RateLimiter) bucket(subject string, limit RateLimit, now time.Time) *tokenBucket {
+	bucket, ok := l.buckets[subject]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[subject] = bucket
+		return bucket
+	}
+
+	rate := float64(limit.Requests) / limit.Period.Seconds()
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+	return bucket
+}
+
+// sweep drops buckets that have not been used for an hour, at most once per minute.
+// The caller holds l.mu.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for subject, bucket := range l.buckets {
+		if now.Sub(bucket.last) > time.Hour {
+			delete(l.buckets, subject)
+		}
+	}
+}
+
+// rateLimitKeysFromContext returns what the call is counted by, among the kinds given
+func rateLimitKeysFromContext(ctx context.Context, fullMethod string, proxies TrustedProxies, kinds []RateLimitKey) map[RateLimitKey]string {
+	keys := make(map[RateLimitKey]string, len(kinds))
+	for _, kind := range kinds {
+		switch kind {
+		case RateLimitKeyAPIKey:
+			if claims, ok := ClaimsFromContext(ctx); ok {
+				keys[kind], _ = claims["api_key_id"].(string)
+			}
+		case RateLimitKeyTenant:
+			keys[kind] = tenantFromContext(ctx)
+		case RateLimitKeyIP:
+			keys[kind] = clientIP(ctx, proxies)
+		case RateLimitKeyRoute:
+			keys[kind] = fullMethod
+		}
+	}
+	return keys
+}
+
+// clientIP returns the address of the client. Calls of the in-process gateway carry the client
+// IP of their HTTP request, and calls of the gateway in endpoint mode send it from loopback;
+// other calls use the peer address, or the X-Forwarded-For metadata when the peer is a trusted proxy.
+func clientIP(ctx context.Context, proxies TrustedProxies) string {
+	if ip, ok := clientIPFromContext(ctx); ok {
+		return ip
+	}
+	var peerAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		peerAddr = p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	// The gateway adds its value after any forwarded from request headers
+	if gateway := md.Get(gatewayClientIPMetadataKey); len(gateway) > 0 && isLoopback(peerAddr) {
+		return gateway[len(gateway)-1]
+	}
+	return proxies.clientIP(peerAddr, md.Get(forwardedForMetadataKey))
+}
+
+// rateLimitStateKey is the context key of the rateLimitState of a call
+type rateLimitStateKey struct{}
+
+// rateLimitState is the tightest bucket a call was counted against by the rate limit
+// interceptors of the chain
+type rateLimitState struct {
+	result *rateLimitResult
+}
+
+// tighten keeps the result with the fewest requests left; a rejection always replaces the result
+func (s *rateLimitState) tighten(result *rateLimitResult, ok bool) {
+	if result != nil && (s.result == nil || !ok || result.remaining < s.result.remaining) {
+		s.result = result
+	}
+}
+
+// UnaryRateLimitInterceptor rejects calls exceeding the rate limits of the given kinds with
+// ResourceExhausted. The chain may hold several, e.g. anonymousRateLimitKeys before
+// UnaryAuthInterceptor and authenticatedRateLimitKeys after UnaryTenantInterceptor; the outermost
+// reports the tightest limit of all in the RateLimit-Limit and RateLimit-Remaining headers:
+// the size of its bucket (the burst) and the requests left in it.
+// Client IPs are read from the gateway's metadata or the X-Forwarded-For metadata of trusted proxies only.
+func UnaryRateLimitInterceptor(limiter *RateLimiter, proxies TrustedProxies, kinds ...RateLimitKey) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		result, ok := limiter.allow(rateLimitKeysFromContext(ctx, info.FullMethod, proxies, kinds), time.Now())
+
+		state, inner := ctx.Value(rateLimitStateKey{}).(*rateLimitState)
+		if !inner {
+			state = &rateLimitState{}
+			ctx = context.WithValue(ctx, rateLimitStateKey{}, state)
+		}
+		state.tighten(result, ok)
+
+		var resp any
+		var err error
+		if ok {
+			resp, err = handler(ctx, req)
+		} else {
+			err = resourceExhaustedError(result.subject,
+				fmt.Sprintf("rate limit of %d requests per %s exceeded", result.limit.Requests, result.limit.Period),
+				result.retryAfter,
+			)
+		}
+
+		// Headers set before the handler returns are sent with the response
+		if !inner && state.result != nil {
+			grpc.SetHeader(ctx, metadata.Pairs(
+				rateLimitLimitMetadataKey, strconv.Itoa(state.result.limit.Burst),
+				rateLimitRemainingMetadataKey, strconv.Itoa(state.result.remaining),
+			))
+		}
+		return resp, err
+	}
+}
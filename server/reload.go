@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadableSettings are the configuration keys applied by Reloader without a restart
+var reloadableSettings = map[string]bool{
+	"log_level":            true,
+	"log_redact":           true,
+	"log_errors":           true,
+	"expose_panic_details": true,
+	"error_format":         true,
+	"rate_limits":          true,
+	"request_headers":      true,
+	"response_headers":     true,
+	"jwt_hs256_secret":     true,
+	"jwt_public_key":       true,
+	"jwt_jwks":             true,
+	"jwt_issuer":           true,
+	"jwt_audience":         true,
+	"jwt_leeway":           true,
+}
+
+// Reloader reloads the configuration and applies the reloadable settings to the running server.
+// Requests in flight finish with the settings they started with; later requests use the new ones.
+type Reloader struct {
+	// args are the command line flags, which keep overriding the configuration file
+	args []string
+
+	logLevel        *slog.LevelVar
+	logRedactor     *LogRedactor
+	errorHandler    *ReloadableErrorHandler
+	requestHeaders  *ReloadableHeaderMatcher
+	responseHeaders *ReloadableHeaderMatcher
+	bindings        HeaderBindings
+	rateLimiter     *RateLimiter
+	authenticator   *Authenticator
+
+	mu      sync.Mutex
+	current *Config
+}
+
+// Reload reads the configuration again and applies it.
+// An invalid configuration, JWT key or API key file leaves the current settings in place.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := loadConfig(r.args)
+	if err != nil {
+		return err
+	}
+
+	// Load the keys before applying anything so that a failure changes nothing
+	var restart []string
+	var jwtVerifier *JWTVerifier
+	if jwtVerifier, err = newJWTVerifier(cfg); err != nil {
+		return fmt.Errorf("load JWT keys: %w", err)
+	}
+	if (jwtVerifier == nil) != (r.authenticator.JWT == nil) {
+		// Enabling or disabling JWT authentication changes the interceptor chain
+		restart = append(restart, "jwt keys (enabling or disabling JWT authentication)")
+		jwtVerifier = nil
+	}
+	if r.authenticator.APIKeys != nil {
+		if err := r.authenticator.APIKeys.Reload(); err != nil {
+			return fmt.Errorf("reload API keys: %w", err)
+		}
+	}
+
+	r.logLevel.Set(cfg.LogLevel)
+	r.logRedactor.SetKeys(cfg.LogRedact)
+	r.errorHandler.Store(newErrorHandler(cfg))
+	r.requestHeaders.Store(IncomingHeaderMatcher(requestHeaderRules(cfg, r.bindings)))
+	r.responseHeaders.Store(OutgoingHeaderMatcher(cfg.ResponseHeaders))
+	r.rateLimiter.SetLimits(cfg.RateLimits)
+	if jwtVerifier != nil {
+		r.authenticator.JWT.replace(jwtVerifier)
+	}
+
+	restart = append(restart, changedSettings(r.current, cfg)...)
+	if len(restart) > 0 {
+		slog.Warn("Configuration changes need a restart to take effect", "settings", restart)
+	}
+	r.current = cfg
+	return nil
+}
+
+// reload reloads and logs the outcome
+func (r *Reloader) reload(reason string) {
+	if err := r.Reload(); err != nil {
+		slog.Error("Configuration reload failed; keeping the current settings", "reason", reason, "error", err)
+		return
+	}
+	slog.Info("Configuration reloaded", "reason", reason)
+}
+
+// changedSettings lists the settings that differ and cannot be reloaded
+func changedSettings(old, cfg *Config) []string {
+	var changed []string
+	oldValue, newValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(cfg).Elem()
+	for i := 0; i < oldValue.NumField(); i++ {
+		name := oldValue.Type().Field(i).Tag.Get("yaml")
+		if name == "-" || reloadableSettings[name] {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// watchFiles calls reload shortly after one of the files changes, until ctx is done.
+// The directories are watched rather than the files so that files replaced by a rename
+// (editors, Kubernetes ConfigMap updates through the ..data symlink) are seen too.
+func watchFiles(ctx context.Context, reload func(reason string), paths ...string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	files := map[string]bool{}
+	dirs := map[string]bool{}
+	for _, path := range paths {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			watcher.Close()
+			return err
+		}
+		files[path] = true
+		if dir := filepath.Dir(path); !dirs[dir] {
+			if err := watcher.Add(dir); err != nil {
+				watcher.Close()
+				return fmt.Errorf("watch %s: %w", dir, err)
+			}
+			dirs[dir] = true
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		// Editors write files in several steps; reload once they are done
+		var timer *time.Timer
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !files[filepath.Clean(event.Name)] && filepath.Base(event.Name) != "..data" {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				name := event.Name
+				timer = time.AfterFunc(200*time.Millisecond, func() { reload("changed " + name) })
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("Watching configuration files failed", "error", err)
+			}
+		}
+	}()
+	return nil
+}
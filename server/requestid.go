@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// requestIDHeader carries the request ID of HTTP requests and responses
+	requestIDHeader = "X-Request-ID"
+	// requestIDMetadataKey carries the request ID of gRPC calls and responses
+	requestIDMetadataKey = "x-request-id"
+	// traceparentMetadataKey is the W3C Trace Context header, forwarded as metadata
+	traceparentMetadataKey = "traceparent"
+)
+
+// requestIDPattern restricts client supplied request IDs to values that are safe in logs and headers
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:/+=@-]{1,128}$`)
+
+// traceparentPattern matches a W3C traceparent header: version, trace ID, parent ID and flags
+var traceparentPattern = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}`)
+
+// resolveRequestID returns the request ID of a request: the one the client sent, the trace ID of
+// its traceparent, or a new random ID in the trace ID format
+func resolveRequestID(requestID, traceparent string) string {
+	if requestIDPattern.MatchString(requestID) {
+		return requestID
+	}
+	if match := traceparentPattern.FindStringSubmatch(strings.ToLower(traceparent)); match != nil && strings.Trim(match[1], "0") != "" {
+		return match[1]
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// requestIDFromContext returns the request ID of the request, or "" outside requests
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// RequestIDMiddleware gives each HTTP request a request ID (see resolveRequestID).
+// The ID is forwarded to the service in the X-Request-ID header, which the gateway turns into
+// metadata, and returned in the X-Request-ID header of every response.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := resolveRequestID(r.Header.Get(requestIDHeader), r.Header.Get("Traceparent"))
+
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID))
+		r.Header = r.Header.Clone()
+		r.Header.Set(requestIDHeader, requestID)
+		next.ServeHTTP(&requestIDResponseWriter{ResponseWriter: w, requestID: requestID}, r)
+	})
+}
+
+// requestIDResponseWriter sets the X-Request-ID header when the response headers are written,
+// replacing the copies added from response metadata or replayed responses
+type requestIDResponseWriter struct {
+	http.ResponseWriter
+	requestID   string
+	wroteHeader bool
+}
+
+// WriteHeader sets the request ID header
+func (w *requestIDResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.Header().Set(requestIDHeader, w.requestID)
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write sets the request ID header of responses written without WriteHeader
+func (w *requestIDResponseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (w *requestIDResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// UnaryRequestIDInterceptor gives each call a request ID from its x-request-id or traceparent
+// metadata (see resolveRequestID), stores it in the incoming metadata for the handlers and returns
+// it in the x-request-id response header. It must be the first interceptor.
+func UnaryRequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var requestID, traceparent string
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 {
+			requestID = values[0]
+		}
+		if values := md.Get(traceparentMetadataKey); len(values) > 0 {
+			traceparent = values[0]
+		}
+		requestID = resolveRequestID(requestID, traceparent)
+
+		md = md.Copy()
+		md.Set(requestIDMetadataKey, requestID)
+		ctx = metadata.NewIncomingContext(ctx, md)
+		ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+		grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+		return handler(ctx, req)
+	}
+}
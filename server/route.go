@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestRoute holds the route pattern the gateway matched for a request
+type requestRoute struct {
+	pattern string
+}
+
+// routeKey is the context key of the requestRoute
+type routeKey struct{}
+
+// withRequestRoute returns a context in which RouteMiddleware records the route matched for
+// the request. Middleware wrapping the gateway reads it once the request is handled.
+func withRequestRoute(ctx context.Context) (context.Context, *requestRoute) {
+	if route, ok := ctx.Value(routeKey{}).(*requestRoute); ok {
+		return ctx, route
+	}
+	route := &requestRoute{}
+	return context.WithValue(ctx, routeKey{}, route), route
+}
+
+// routePattern returns the pattern the gateway matched for the request, such as /v1/unstructured-data/{id}
+func routePattern(r *http.Request) (string, bool) {
+	pattern, ok := runtime.HTTPPattern(r.Context())
+	if !ok {
+		return "", false
+	}
+	return strings.ReplaceAll(pattern.String(), "=*}", "}"), true
+}
+
+// RouteMiddleware records the route pattern matched by the gateway for the request-scoped
+// logger, the span of the request and the middleware wrapping the gateway.
+// Register it with runtime.WithMiddlewares.
+func RouteMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		if pattern, ok := routePattern(r); ok {
+			setLogAttr(r.Context(), "route", pattern)
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + pattern)
+			span.SetAttributes(attribute.String("http.route", pattern))
+			if route, ok := r.Context().Value(routeKey{}).(*requestRoute); ok {
+				route.pattern = pattern
+			}
+		}
+		next(w, r, pathParams)
+	}
+}
@@ -2,77 +2,82 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	pb "protobuf-http-golang/pb"
+	"slices"
+	"strings"
+	"time"
 
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/metadata"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "protobuf-http-golang/pb"
 )
 
+// unstructuredDataResource is the resource type reported in errors about stored data
+const unstructuredDataResource = "unstructured-data"
+
+// unstructuredDataDescriptor validates the field masks of the unstructured data methods
+var unstructuredDataDescriptor = (&pb.UnstructuredData{}).ProtoReflect().Descriptor()
+
+// unstructuredDataOutputOnly lists the fields that updates never overwrite
+var unstructuredDataOutputOnly = map[string]bool{
+	"id":          true,
+	"create_time": true,
+	"update_time": true,
+}
+
 // server implements the DiscoverServiceServer interface
 type server struct {
 	pb.UnimplementedDiscoverServiceServer
+	store      Store
+	pageTokens *pageTokenCodec
 }
 
 // GetParamInBody implements the GetParamInBody RPC method
 func (s *server) GetParamInBody(ctx context.Context, req *pb.GetParamInBodyRequest) (*pb.Response, error) {
-	log.Printf("GetParamInBody called with id: %s, content: %s", req.Id, req.Content)
+	// The content may hold personal data; only its size is logged
+	LoggerFromContext(ctx).Debug("GetParamInBody called", "id", req.Id, "content_bytes", len(req.Content))
 
 	// Example error handling: validate required fields
+	var violations []*errdetails.BadRequest_FieldViolation
 	if req.Id == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "id is required")
+		violations = append(violations, fieldViolation("id", "id is required"))
 	}
-
 	if req.Content == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "content is required")
+		violations = append(violations, fieldViolation("content", "content is required"))
+	}
+	if len(violations) > 0 {
+		return nil, invalidArgumentError(violations...)
 	}
 
 	// Example error handling: simulate resource not found
 	if req.Id == "not-found" {
-		return nil, status.Errorf(codes.NotFound, "resource with id '%s' not found", req.Id)
-	}
-
-	// Example error handling: simulate permission denied
-	if req.Id == "unauthorized" {
-		return nil, status.Errorf(codes.PermissionDenied, "access denied for id '%s'", req.Id)
+		return nil, notFoundError("parameter", req.Id)
 	}
 
 	// Example error handling: simulate internal server error
 	if req.Id == "error" {
-		return nil, status.Errorf(codes.Internal, "internal server error occurred")
+		return nil, internalError("internal server error occurred")
 	}
 
-	return &pb.Response{
+	response := &pb.Response{
 		NewContent: fmt.Sprintf("Processed ID: %s, Content: %s", req.Id, req.Content),
-	}, nil
+	}
+	setETag(ctx, response)
+	return response, nil
 }
 
-// GetParamInHeader implements the GetParamInHeader RPC method
+// GetParamInHeader implements the GetParamInHeader RPC method.
+// The id is bound to the X-Custom-Header-Id header by its (bind) option.
 func (s *server) GetParamInHeader(ctx context.Context, req *pb.GetParamInHeaderRequest) (*pb.Response, error) {
-	// Access HTTP headers from gRPC metadata
-	md, ok := metadata.FromIncomingContext(ctx)
-	if ok {
-		// Get specific headers - try both lowercase and original case
-		if IDFromHeader := md.Get("x-custom-header-id"); len(IDFromHeader) > 0 {
-			log.Printf("Found x-custom-header-id: %s", IDFromHeader[0])
-			req.Id = IDFromHeader[0]
-		} else {
-			log.Printf("No x-custom-header-id found in metadata")
-		}
-	} else {
-		log.Printf("No metadata found in context")
-	}
-
-	// Example error handling: validate required header
-	if req.Id == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "x-custom-header-id header is required")
-	}
+	LoggerFromContext(ctx).Debug("GetParamInHeader called", "id", req.Id)
 
 	// Example error handling: simulate authentication error
 	if req.Id == "invalid-token" {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid authentication token")
+		return nil, unauthenticatedError("INVALID_TOKEN", "invalid authentication token")
 	}
 
 	return &pb.Response{
@@ -87,27 +92,195 @@ func (s *server) PostUnstructuredData(
 ) (*pb.PostUnstructuredDataResponse, error) {
 
 	// Example error handling: validate required fields
+	var violations []*errdetails.BadRequest_FieldViolation
 	if req.Id == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "id is required")
+		violations = append(violations, fieldViolation("id", "id is required"))
 	}
-
 	if req.Data == nil {
-		return nil, status.Errorf(codes.InvalidArgument, "data is required")
+		violations = append(violations, fieldViolation("data", "data is required"))
 	}
-
-	// Example error handling: simulate resource already exists
-	if req.Id == "duplicate" {
-		return nil, status.Errorf(codes.AlreadyExists, "resource with id '%s' already exists", req.Id)
+	if len(violations) > 0 {
+		return nil, invalidArgumentError(violations...)
 	}
 
-	// Example error handling: simulate rate limiting
-	if req.Id == "rate-limit" {
-		return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded")
+	now := time.Now().UTC()
+	record := &Record{
+		ID:         req.Id,
+		Tenant:     tenantFromContext(ctx),
+		Data:       req.Data,
+		Labels:     req.Labels,
+		CreateTime: now,
+		UpdateTime: now,
 	}
+	if err := s.store.Create(ctx, record); err != nil {
+		return nil, s.storeError(ctx, req.Id, err)
+	}
+	setETag(ctx, recordToProto(record))
 
-	// Echo back the received data
 	return &pb.PostUnstructuredDataResponse{
-		Id:   req.Id,
-		Data: req.Data,
+		Id:     record.ID,
+		Data:   record.Data,
+		Labels: record.Labels,
 	}, nil
 }
+
+// GetUnstructuredData implements the GetUnstructuredData RPC method.
+// A read_mask limits the response to the listed fields.
+func (s *server) GetUnstructuredData(ctx context.Context, req *pb.GetUnstructuredDataRequest) (*pb.UnstructuredData, error) {
+	var violations []*errdetails.BadRequest_FieldViolation
+	if req.Id == "" {
+		violations = append(violations, fieldViolation("id", "id is required"))
+	}
+	readMask, maskViolations := normalizeFieldMask(req.ReadMask, unstructuredDataDescriptor, "read_mask")
+	violations = append(violations, maskViolations...)
+	if len(violations) > 0 {
+		return nil, invalidArgumentError(violations...)
+	}
+
+	record, err := s.store.Get(ctx, tenantFromContext(ctx), req.Id)
+	if err != nil {
+		return nil, s.storeError(ctx, req.Id, err)
+	}
+
+	// The ETag versions the representation sent, so that each read mask has its own
+	data := recordToProto(record)
+	applyReadMask(data, readMask)
+	setETag(ctx, data)
+	return data, nil
+}
+
+// ListUnstructuredData implements the ListUnstructuredData RPC method
+func (s *server) ListUnstructuredData(ctx context.Context, req *pb.ListUnstructuredDataRequest) (*pb.ListUnstructuredDataResponse, error) {
+	if req.PageSize < 0 {
+		return nil, invalidArgumentError(fieldViolation("page_size", "page_size must not be negative"))
+	}
+	pageSize := effectivePageSize(req.PageSize)
+
+	tenant := tenantFromContext(ctx)
+	var cursor pageCursor
+	if req.PageToken != "" {
+		var err error
+		// A page token only continues a listing of the tenant it was issued to
+		if cursor, err = s.pageTokens.decode(req.PageToken); err != nil || cursor.Tenant != tenant {
+			return nil, invalidArgumentError(fieldViolation("page_token", "page_token is invalid or expired"))
+		}
+	}
+
+	// Fetch one extra record to find out whether there is a next page
+	records, err := s.store.List(ctx, tenant, cursor.AfterID, pageSize+1)
+	if err != nil {
+		return nil, s.storeError(ctx, "", err)
+	}
+
+	response := &pb.ListUnstructuredDataResponse{}
+	if len(records) > pageSize {
+		records = records[:pageSize]
+		token, err := s.pageTokens.encode(pageCursor{AfterID: records[len(records)-1].ID, Tenant: tenant})
+		if err != nil {
+			LoggerFromContext(ctx).Error("Failed to encode page token", "error", err)
+			return nil, internalError("failed to create page token")
+		}
+		response.NextPageToken = token
+	}
+
+	response.UnstructuredData = make([]*pb.UnstructuredData, 0, len(records))
+	for _, record := range records {
+		response.UnstructuredData = append(response.UnstructuredData, recordToProto(record))
+	}
+	return response, nil
+}
+
+// UpdateUnstructuredData implements the UpdateUnstructuredData RPC method.
+// Only the fields listed in update_mask are overwritten; without a mask the populated fields
+// of the request are. Output only fields (id, create_time, update_time) are ignored.
+func (s *server) UpdateUnstructuredData(ctx context.Context, req *pb.UpdateUnstructuredDataRequest) (*pb.UnstructuredData, error) {
+	var violations []*errdetails.BadRequest_FieldViolation
+	if req.Id == "" {
+		violations = append(violations, fieldViolation("id", "id is required"))
+	}
+	if req.UnstructuredData == nil {
+		violations = append(violations, fieldViolation("unstructured_data", "unstructured_data is required"))
+	} else if req.UnstructuredData.Id != "" && req.UnstructuredData.Id != req.Id {
+		violations = append(violations, fieldViolation("unstructured_data.id", "id cannot be changed"))
+	}
+	updateMask, maskViolations := normalizeFieldMask(req.UpdateMask, unstructuredDataDescriptor, "update_mask")
+	violations = append(violations, maskViolations...)
+	// data is required, so the mask may only name it along with a new value
+	if req.UnstructuredData != nil && req.UnstructuredData.Data == nil &&
+		(slices.Contains(updateMask, "data") || slices.Contains(updateMask, wildcardPath)) {
+		violations = append(violations, fieldViolation("unstructured_data.data", "data is required and cannot be cleared by update_mask"))
+	}
+	if len(violations) > 0 {
+		return nil, invalidArgumentError(violations...)
+	}
+
+	if len(updateMask) == 0 {
+		updateMask = populatedFieldPaths(req.UnstructuredData)
+	}
+	updateMask = slices.DeleteFunc(updateMask, func(path string) bool {
+		return unstructuredDataOutputOnly[strings.SplitN(path, ".", 2)[0]]
+	})
+
+	record, err := s.store.Update(ctx, tenantFromContext(ctx), req.Id, func(record *Record) error {
+		data := recordToProto(record)
+		// Optimistic concurrency: the If-Match ETag must match the stored version
+		if err := checkIfMatch(ctx, req.Id, data); err != nil {
+			return err
+		}
+		applyUpdateMask(data, req.UnstructuredData, updateMask)
+
+		record.Data = data.Data
+		record.Labels = data.Labels
+		record.UpdateTime = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, s.storeError(ctx, req.Id, err)
+	}
+
+	data := recordToProto(record)
+	setETag(ctx, data)
+	return data, nil
+}
+
+// DeleteUnstructuredData implements the DeleteUnstructuredData RPC method
+func (s *server) DeleteUnstructuredData(ctx context.Context, req *pb.DeleteUnstructuredDataRequest) (*emptypb.Empty, error) {
+	if req.Id == "" {
+		return nil, invalidArgumentError(fieldViolation("id", "id is required"))
+	}
+
+	if err := s.store.Delete(ctx, tenantFromContext(ctx), req.Id); err != nil {
+		return nil, s.storeError(ctx, req.Id, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// storeError converts a Store error into a gRPC status error.
+// Status errors returned by update functions are passed through.
+func (s *server) storeError(ctx context.Context, id string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return notFoundError(unstructuredDataResource, id)
+	case errors.Is(err, ErrAlreadyExists):
+		return alreadyExistsError(unstructuredDataResource, id)
+	default:
+		LoggerFromContext(ctx).Error("Store operation on unstructured data failed", "id", id, "error", err)
+		return internalError("failed to access unstructured data")
+	}
+}
+
+// recordToProto converts a stored record into its API representation
+func recordToProto(record *Record) *pb.UnstructuredData {
+	return &pb.UnstructuredData{
+		Id:         record.ID,
+		Data:       record.Data,
+		Labels:     record.Labels,
+		CreateTime: timestamppb.New(record.CreateTime),
+		UpdateTime: timestamppb.New(record.UpdateTime),
+	}
+}
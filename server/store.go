@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Errors returned by Store implementations
+var (
+	ErrNotFound      = errors.New("record not found")
+	ErrAlreadyExists = errors.New("record already exists")
+)
+
+// Record is a stored unstructured data payload
+type Record struct {
+	// Tenant owns the record; ids are unique within a tenant
+	Tenant     string
+	ID         string
+	Data       *anypb.Any
+	Labels     map[string]string
+	CreateTime time.Time
+	UpdateTime time.Time
+}
+
+// clone returns a deep copy of the record so callers cannot mutate stored state
+func (r *Record) clone() *Record {
+	c := *r
+	if r.Data != nil {
+		c.Data = proto.Clone(r.Data).(*anypb.Any)
+	}
+	c.Labels = maps.Clone(r.Labels)
+	return &c
+}
+
+// Store persists unstructured data records.
+// Records are partitioned by tenant: no method reads or changes the records of another tenant.
+type Store interface {
+	// Create stores a new record for record.Tenant, returning ErrAlreadyExists if the id is taken
+	Create(ctx context.Context, record *Record) error
+	// Get returns the record of the tenant with the given id, or ErrNotFound
+	Get(ctx context.Context, tenant, id string) (*Record, error)
+	// List returns up to limit records of the tenant ordered by id, starting after the record with id afterID
+	List(ctx context.Context, tenant, afterID string, limit int) ([]*Record, error)
+	// Update atomically applies fn to the record of the tenant with the given id and stores the result.
+	// It returns ErrNotFound if there is no such record; an error returned by fn aborts the update.
+	Update(ctx context.Context, tenant, id string, fn func(record *Record) error) (*Record, error)
+	// Delete removes the record of the tenant with the given id, or returns ErrNotFound
+	Delete(ctx context.Context, tenant, id string) error
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// StoreKind selects the Store implementation
+type StoreKind string
+
+const (
+	// StoreKindMemory keeps records in memory; they are lost on restart
+	StoreKindMemory StoreKind = "memory"
+	// StoreKindBolt keeps records in an embedded BoltDB file
+	StoreKindBolt StoreKind = "bolt"
+)
+
+// openStore opens the store selected by the configuration
+func openStore(cfg *Config) (Store, error) {
+	switch cfg.StoreKind {
+	case StoreKindMemory:
+		return NewMemoryStore(), nil
+	case StoreKindBolt:
+		return OpenBoltStore(cfg.StorePath)
+	default:
+		return nil, fmt.Errorf("unknown store %q", cfg.StoreKind)
+	}
+}
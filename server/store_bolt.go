@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// boltBucket is the bucket holding the unstructured data records, in one nested bucket per tenant
+var boltBucket = []byte("unstructured_data")
+
+// boltRecord is the on-disk encoding of a Record
+type boltRecord struct {
+	ID string `json:"id"`
+	// Data is nil for records without data, unlike records with an empty Any
+	Data       *boltAny          `json:"data,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	CreateTime time.Time         `json:"create_time"`
+	UpdateTime time.Time         `json:"update_time"`
+}
+
+// boltAny is the on-disk encoding of the data of a Record
+type boltAny struct {
+	TypeURL string `json:"type_url"`
+	Value   []byte `json:"value"`
+}
+
+// BoltStore is a Store backed by an embedded BoltDB file
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens (or creates) the BoltDB file at path
+func OpenBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create store directory: %w", err)
+	}
+
+	// Fail instead of blocking forever when another process holds the file lock
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open bolt store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("prepare bolt bucket: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// tenantBucket returns the bucket of the tenant, or nil if the tenant has no records yet
+func tenantBucket(tx *bolt.Tx, tenant string) *bolt.Bucket {
+	return tx.Bucket(boltBucket).Bucket([]byte(tenant))
+}
+
+// Create implements Store
+func (s *BoltStore) Create(ctx context.Context, record *Record) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(boltBucket).CreateBucketIfNotExists([]byte(record.Tenant))
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(record.ID)) != nil {
+			return ErrAlreadyExists
+		}
+		return putBoltRecord(bucket, record)
+	})
+}
+
+// Get implements Store
+func (s *BoltStore) Get(ctx context.Context, tenant, id string) (*Record, error) {
+	var record *Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tenantBucket(tx, tenant)
+		if bucket == nil {
+			return ErrNotFound
+		}
+		value := bucket.Get([]byte(id))
+		if value == nil {
+			return ErrNotFound
+		}
+		var err error
+		record, err = decodeBoltRecord(tenant, value)
+		return err
+	})
+	return record, err
+}
+
+// List implements Store
+func (s *BoltStore) List(ctx context.Context, tenant, afterID string, limit int) ([]*Record, error) {
+	var records []*Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tenantBucket(tx, tenant)
+		if bucket == nil {
+			return nil
+		}
+		// Bolt keeps keys sorted, so seeking to afterID yields the records ordered by id
+		cursor := bucket.Cursor()
+		key, value := cursor.Seek([]byte(afterID))
+		if key != nil && string(key) == afterID {
+			key, value = cursor.Next()
+		}
+		for ; key != nil && len(records) < limit; key, value = cursor.Next() {
+			record, err := decodeBoltRecord(tenant, value)
+			if err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// Update implements Store
+func (s *BoltStore) Update(ctx context.Context, tenant, id string, fn func(record *Record) error) (*Record, error) {
+	var record *Record
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tenantBucket(tx, tenant)
+		if bucket == nil {
+			return ErrNotFound
+		}
+		value := bucket.Get([]byte(id))
+		if value == nil {
+			return ErrNotFound
+		}
+		var err error
+		record, err = decodeBoltRecord(tenant, value)
+		if err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+		record.Tenant, record.ID = tenant, id
+		return putBoltRecord(bucket, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Delete implements Store
+func (s *BoltStore) Delete(ctx context.Context, tenant, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tenantBucket(tx, tenant)
+		if bucket == nil || bucket.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// Close implements Store
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// putBoltRecord encodes the record and writes it to the bucket
+func putBoltRecord(bucket *bolt.Bucket, record *Record) error {
+	stored := boltRecord{
+		ID:         record.ID,
+		Labels:     record.Labels,
+		CreateTime: record.CreateTime,
+		UpdateTime: record.UpdateTime,
+	}
+	if record.Data != nil {
+		stored.Data = &boltAny{TypeURL: record.Data.GetTypeUrl(), Value: record.Data.GetValue()}
+	}
+	value, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("encode record %s: %w", record.ID, err)
+	}
+	return bucket.Put([]byte(record.ID), value)
+}
+
+// decodeBoltRecord decodes a record read from the bucket of the tenant
+func decodeBoltRecord(tenant string, value []byte) (*Record, error) {
+	var stored boltRecord
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return nil, fmt.Errorf("decode record: %w", err)
+	}
+	record := &Record{
+		Tenant:     tenant,
+		ID:         stored.ID,
+		Labels:     stored.Labels,
+		CreateTime: stored.CreateTime,
+		UpdateTime: stored.UpdateTime,
+	}
+	if stored.Data != nil {
+		record.Data = &anypb.Any{TypeUrl: stored.Data.TypeURL, Value: stored.Data.Value}
+	}
+	return record, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryStore is a Store that keeps records in memory
+type MemoryStore struct {
+	mu sync.RWMutex
+	// tenants maps tenant to its records
+	tenants map[string]*memoryTenant
+}
+
+// memoryTenant holds the records of a tenant
+type memoryTenant struct {
+	// records maps id to the record
+	records map[string]*Record
+	// ids are the ids of the records in order, so that List pages without sorting
+	ids []string
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tenants: make(map[string]*memoryTenant)}
+}
+
+// Create implements Store
+func (s *MemoryStore) Create(ctx context.Context, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenant, ok := s.tenants[record.Tenant]
+	if !ok {
+		tenant = &memoryTenant{records: make(map[string]*Record)}
+		s.tenants[record.Tenant] = tenant
+	}
+	i, found := slices.BinarySearch(tenant.ids, record.ID)
+	if found {
+		return ErrAlreadyExists
+	}
+	tenant.records[record.ID] = record.clone()
+	tenant.ids = slices.Insert(tenant.ids, i, record.ID)
+	return nil
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(ctx context.Context, tenant, id string) (*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.record(tenant, id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return record.clone(), nil
+}
+
+// List implements Store
+func (s *MemoryStore) List(ctx context.Context, tenant, afterID string, limit int) ([]*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tenants[tenant]
+	if !ok {
+		return []*Record{}, nil
+	}
+	start, found := slices.BinarySearch(t.ids, afterID)
+	if found {
+		start++
+	}
+	ids := t.ids[start:]
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	records := make([]*Record, 0, len(ids))
+	for _, id := range ids {
+		records = append(records, t.records[id].clone())
+	}
+	return records, nil
+}
+
+// Update implements Store
+func (s *MemoryStore) Update(ctx context.Context, tenant, id string, fn func(record *Record) error) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.record(tenant, id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	// fn works on a copy so a failed update leaves the stored record untouched
+	updated := current.clone()
+	if err := fn(updated); err != nil {
+		return nil, err
+	}
+	updated.Tenant, updated.ID = tenant, id
+	s.tenants[tenant].records[id] = updated
+	return updated.clone(), nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(ctx context.Context, tenant, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tenants[tenant]
+	if !ok {
+		return ErrNotFound
+	}
+	i, found := slices.BinarySearch(t.ids, id)
+	if !found {
+		return ErrNotFound
+	}
+	delete(t.records, id)
+	t.ids = slices.Delete(t.ids, i, i+1)
+	return nil
+}
+
+// record returns the stored record of the tenant; the caller holds the lock
+func (s *MemoryStore) record(tenant, id string) (*Record, bool) {
+	t, ok := s.tenants[tenant]
+	if !ok {
+		return nil, false
+	}
+	record, ok := t.records[id]
+	return record, ok
+}
+
+// Close implements Store
+func (s *MemoryStore) Close() error {
+	return nil
+}
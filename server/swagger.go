@@ -1,16 +1,17 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 )
 
-func SwaggerUI() {
+// swaggerHandler serves the Swagger UI and the OpenAPI document in file under /swagger-ui/
+func swaggerHandler(file string) http.Handler {
 	swaggerMux := http.NewServeMux()
 	swaggerMux.HandleFunc("/swagger-ui/", func(w http.ResponseWriter, r *http.Request) {
 		// Serve the swagger.json file
 		if r.URL.Path == "/swagger-ui/swagger.json" {
-			http.ServeFile(w, r, "pb/discover.swagger.json")
+			http.ServeFile(w, r, file)
 			return
 		}
 
@@ -41,13 +42,18 @@ func SwaggerUI() {
 		`))
 	})
 
+	return swaggerMux
+}
+
+// SwaggerUI serves the Swagger UI on its own port
+func SwaggerUI(addr, file string) {
 	swaggerServer := &http.Server{
-		Addr:    ":8081",
-		Handler: swaggerMux,
+		Addr:    addr,
+		Handler: swaggerHandler(file),
 	}
 
-	log.Printf("Starting Swagger UI server on %s", swaggerServer.Addr)
+	slog.Info("Starting Swagger UI server", "addr", swaggerServer.Addr)
 	if err := swaggerServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Failed to serve Swagger UI: %v", err)
+		fatal("Failed to serve Swagger UI", "error", err)
 	}
 }
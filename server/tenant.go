@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"regexp"
+
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+const (
+	// tenantMetadataKey is the metadata key the gateway forwards the X-Tenant-Id header as
+	tenantMetadataKey = "x-tenant-id"
+	// defaultTenant owns the requests that name no tenant, unless a tenant is required
+	defaultTenant = "default"
+)
+
+// tenantIDPattern restricts tenant ids to names that are safe in logs, keys and file names
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$`)
+
+// TenantResolver determines the tenant of a call.
+// The tenant of an authenticated caller comes from a claim of its credentials and cannot be
+// overridden; credentials without the claim belong to the default tenant. Only callers of a
+// server without authentication name their tenant with the X-Tenant-Id header.
+type TenantResolver struct {
+	// Claim is the token claim holding the tenant of the caller
+	Claim string
+	// Required rejects calls without a tenant instead of assigning them to the default tenant,
+	// including those of credentials without the claim
+	Required bool
+}
+
+// resolve returns the tenant of the call
+func (r *TenantResolver) resolve(ctx context.Context) (string, error) {
+	var requested string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(tenantMetadataKey); len(values) > 0 {
+			requested = values[0]
+		}
+	}
+
+	var bound string
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		bound, _ = claims[r.Claim].(string)
+		if bound == "" && r.Required {
+			return "", permissionDeniedError("TENANT_CLAIM_MISSING",
+				"credentials carry no '"+r.Claim+"' claim and are not bound to a tenant",
+				map[string]string{"claim": r.Claim},
+			)
+		}
+		if bound == "" {
+			// The header never chooses the tenant of an authenticated caller
+			bound = defaultTenant
+		}
+	}
+
+	tenant := requested
+	switch {
+	case bound != "" && requested != "" && requested != bound:
+		return "", permissionDeniedError("TENANT_MISMATCH",
+			"caller belongs to tenant '"+bound+"' and cannot access tenant '"+requested+"'",
+			map[string]string{"tenant": bound, "requested_tenant": requested},
+		)
+	case bound != "":
+		tenant = bound
+	case requested == "" && r.Required:
+		return "", invalidArgumentError(fieldViolation("X-Tenant-Id", "X-Tenant-Id header is required"))
+	case requested == "":
+		return defaultTenant, nil
+	}
+
+	if !tenantIDPattern.MatchString(tenant) {
+		return "", invalidArgumentError(fieldViolation("X-Tenant-Id",
+			"tenant id must be 1-63 letters, digits, '.', '_' or '-', starting with a letter or digit"))
+	}
+	return tenant, nil
+}
+
+// resolveRequest returns the tenant of the call an HTTP request to the gateway makes, authenticating
+// its credentials first when authenticator is enabled, as UnaryAuthInterceptor and UnaryTenantInterceptor will
+func (r *TenantResolver) resolveRequest(req *http.Request, authenticator *Authenticator) (string, error) {
+	md := metadata.MD{}
+	for header, key := range map[string]string{
+		"Authorization": authorizationMetadataKey,
+		"X-API-Key":     apiKeyMetadataKey,
+		"X-Tenant-Id":   tenantMetadataKey,
+	} {
+		if values := req.Header.Values(header); len(values) > 0 {
+			md.Set(key, values...)
+		}
+	}
+
+	ctx := metadata.NewIncomingContext(req.Context(), md)
+	if authenticator.enabled() {
+		claims, err := authenticator.authenticate(ctx)
+		if err != nil {
+			return "", err
+		}
+		ctx = context.WithValue(ctx, claimsContextKey{}, claims)
+	}
+	return r.resolve(ctx)
+}
+
+// UnaryTenantInterceptor resolves the tenant of each call for the handlers (see tenantFromContext)
+// and records it in the request-scoped logger and in the ErrorInfo details of the errors they return.
+// It must run after UnaryAuthInterceptor, which provides the caller claims.
+func UnaryTenantInterceptor(resolver *TenantResolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		tenant, err := resolver.resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		setLogAttr(ctx, "tenant", tenant)
+
+		resp, err := handler(context.WithValue(ctx, tenantContextKey{}, tenant), req)
+		if err != nil {
+			return nil, withTenantDetail(err, tenant)
+		}
+		return resp, nil
+	}
+}
+
+// tenantContextKey is the context key of the tenant of the call
+type tenantContextKey struct{}
+
+// tenantFromContext returns the tenant resolved for the call
+func tenantFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		return tenant
+	}
+	return defaultTenant
+}
+
+// withTenantDetail adds the tenant to the ErrorInfo metadata of a status error,
+// attaching an ErrorInfo if the error has none
+func withTenantDetail(err error, tenant string) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	var details []protoadapt.MessageV1
+	hasErrorInfo := false
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			md := maps.Clone(d.GetMetadata())
+			if md == nil {
+				md = map[string]string{}
+			}
+			md["tenant"] = tenant
+			details = append(details, &errdetails.ErrorInfo{Reason: d.GetReason(), Domain: d.GetDomain(), Metadata: md})
+			hasErrorInfo = true
+		case protoadapt.MessageV1:
+			details = append(details, d)
+		}
+	}
+	if !hasErrorInfo {
+		details = append(details, errorInfo(code.Code(st.Code()).String(), map[string]string{"tenant": tenant}))
+	}
+	return statusWithDetails(st.Code(), st.Message(), details...)
+}
+
+// errorTenant returns the tenant recorded in the details of an error, if any
+func errorTenant(err error) string {
+	st, ok := status.FromError(err)
+	if !ok {
+		return ""
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetMetadata()["tenant"] != "" {
+			return info.GetMetadata()["tenant"]
+		}
+	}
+	return ""
+}
@@ -1,7 +1,49 @@
 #!/bin/bash
 
-# Test script to demonstrate error handling middleware
-BASE_URL="http://localhost:8080"
+# Test script to demonstrate error handling middleware.
+# Most tests run against the server at BASE_URL (./run_server.sh); tests needing other flags
+# start their own server on TEST_URL and skip when it cannot be built or started.
+BASE_URL="${BASE_URL:-http://localhost:8080}"
+TEST_URL="http://localhost:18080"
+SCRIPT_DIR="$(cd "$(dirname "$0")" && pwd)"
+WORK_DIR="$(mktemp -d)"
+TEST_SERVER_PID=""
+
+# build_test_server builds the server for the tests that start their own
+build_test_server() {
+  [ -x "$WORK_DIR/server" ] && return 0
+  command -v go > /dev/null || { echo "go is not installed"; return 1; }
+  (cd "$SCRIPT_DIR" && go build -o "$WORK_DIR/server" .)
+}
+
+# start_test_server starts the server on TEST_URL with the given flags
+start_test_server() {
+  build_test_server || return 1
+  "$WORK_DIR/server" -http-addr :18080 -grpc-addr :19090 -swagger-addr :18081 \
+    -swagger-file "$SCRIPT_DIR/../pb/discover.swagger.json" -metrics-addr "" \
+    -access-log-format off -watch-config=false "$@" > "$WORK_DIR/server.log" 2>&1 &
+  TEST_SERVER_PID=$!
+  for _ in $(seq 1 50); do
+    curl -s -o /dev/null "$TEST_URL/" && return 0
+    kill -0 "$TEST_SERVER_PID" 2> /dev/null || break
+    sleep 0.1
+  done
+  echo "test server did not start:"
+  cat "$WORK_DIR/server.log"
+  stop_test_server
+  return 1
+}
+
+# stop_test_server stops the server started by start_test_server
+stop_test_server() {
+  if [ -n "$TEST_SERVER_PID" ]; then
+    kill "$TEST_SERVER_PID" 2> /dev/null
+    wait "$TEST_SERVER_PID" 2> /dev/null
+    TEST_SERVER_PID=""
+  fi
+}
+
+trap 'stop_test_server; rm -rf "$WORK_DIR"' EXIT
 
 echo "Testing Error Handling Middleware"
 echo "================================="
@@ -27,9 +69,16 @@ echo "4. Testing resource not found:"
 curl -s -X GET "$BASE_URL/v1/get-param-in-body/not-found?content=test-content" | jq .
 echo ""
 
-# Test 5: Permission denied
+# Test 5: Permission denied (server with API keys, key without the admin role)
 echo "5. Testing permission denied:"
-curl -s -X GET "$BASE_URL/v1/get-param-in-body/unauthorized?content=test-content" | jq .
+if build_test_server &&
+  API_KEY=$("$WORK_DIR/server" apikey create -file "$WORK_DIR/api_keys.json" -owner test -tenant test -scopes data.read | sed -n 's/^Key: //p') &&
+  start_test_server -api-keys-file "$WORK_DIR/api_keys.json"; then
+  curl -s -H "X-API-Key: $API_KEY" -X DELETE "$TEST_URL/v1/unstructured-data/valid-id" | jq .
+  stop_test_server
+else
+  echo "Skipped: the test server could not be started"
+fi
 echo ""
 
 # Test 6: Internal server error
@@ -52,18 +101,28 @@ echo "9. Testing valid header request:"
 curl -s -H "X-Custom-Header-Id: valid-token" -X GET "$BASE_URL/v1/get-param-in-header?content=test-content" | jq .
 echo ""
 
-# Test 10: Duplicate resource (POST)
+# Test 10: Duplicate resource (POST the same id twice)
 echo "10. Testing duplicate resource (POST):"
+curl -s -X POST "$BASE_URL/v1/post/unstructured-data" \
+  -H "Content-Type: application/json" \
+  -d '{"id": "duplicate", "data": {"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "test"}}' > /dev/null
 curl -s -X POST "$BASE_URL/v1/post/unstructured-data" \
   -H "Content-Type: application/json" \
   -d '{"id": "duplicate", "data": {"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "test"}}' | jq .
 echo ""
 
-# Test 11: Rate limit exceeded
+# Test 11: Rate limit exceeded (server limited to 5 requests per minute per client IP)
 echo "11. Testing rate limit exceeded:"
-curl -s -X POST "$BASE_URL/v1/post/unstructured-data" \
-  -H "Content-Type: application/json" \
-  -d '{"id": "rate-limit", "data": {"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "test"}}' | jq .
+if start_test_server -insecure-disable-auth -rate-limit-ip 5/m; then
+  for i in $(seq 1 5); do
+    curl -s -o /dev/null "$TEST_URL/v1/unstructured-data"
+  done
+  curl -s -i "$TEST_URL/v1/unstructured-data" | grep -i -E "^(ratelimit|retry-after)"
+  curl -s "$TEST_URL/v1/unstructured-data" | jq .
+  stop_test_server
+else
+  echo "Skipped: the test server could not be started"
+fi
 echo ""
 
 # Test 12: Valid POST request
@@ -73,9 +132,56 @@ curl -s -X POST "$BASE_URL/v1/post/unstructured-data" \
   -d '{"id": "valid-id", "data": {"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "test"}}' | jq .
 echo ""
 
-# Test 13: Request with X-Request-ID header
-echo "13. Testing request with X-Request-ID header:"
-curl -s -H "X-Request-ID: test-request-123" -X GET "$BASE_URL/v1/get-param-in-body/test-id?content=test-content" | jq .
+# Test 13: Request IDs
+echo "13. Testing request IDs (sent, taken from traceparent, generated):"
+curl -s -i -H "X-Request-ID: test-request-123" -X GET "$BASE_URL/v1/get-param-in-body/test-id?content=test-content" | grep -i "x-request-id"
+curl -s -i -H "traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" -X GET "$BASE_URL/v1/get-param-in-body/test-id?content=test-content" | grep -i "x-request-id"
+curl -s -X GET "$BASE_URL/v1/get-param-in-body/not-found?content=test-content" | jq .metadata.request_id
+echo ""
+
+# Test 14: Problem Details format
+echo "14. Testing RFC 9457 Problem Details format:"
+curl -s -H "Accept: application/problem+json" -X GET "$BASE_URL/v1/get-param-in-body/not-found?content=test-content" | jq .
+echo ""
+
+# Test 15: Stale If-Match
+echo "15. Testing stale If-Match on update:"
+curl -s -X PATCH "$BASE_URL/v1/unstructured-data/valid-id" \
+  -H "Content-Type: application/json" \
+  -H 'If-Match: "stale-etag"' \
+  -d '{"labels": {"team": "search"}}' | jq .
+echo ""
+
+# Test 16: Idempotency-Key reused with a different body
+echo "16. Testing Idempotency-Key replay and reuse:"
+curl -s -X POST "$BASE_URL/v1/post/unstructured-data" \
+  -H "Content-Type: application/json" \
+  -H "Idempotency-Key: test-key-16" \
+  -d '{"id": "idempotent-id", "data": {"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "test"}}' | jq .
+curl -s -i -X POST "$BASE_URL/v1/post/unstructured-data" \
+  -H "Content-Type: application/json" \
+  -H "Idempotency-Key: test-key-16" \
+  -d '{"id": "idempotent-id", "data": {"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "test"}}' | grep -i "idempotent-replayed"
+curl -s -X POST "$BASE_URL/v1/post/unstructured-data" \
+  -H "Content-Type: application/json" \
+  -H "Idempotency-Key: test-key-16" \
+  -d '{"id": "other-id", "data": {"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "test"}}' | jq .
+echo ""
+
+# Test 17: Records of one tenant are not visible to another
+echo "17. Testing tenant isolation:"
+curl -s -X POST "$BASE_URL/v1/post/unstructured-data" \
+  -H "Content-Type: application/json" \
+  -H "X-Tenant-Id: tenant-a" \
+  -d '{"id": "tenant-id", "data": {"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "test"}}' | jq .
+curl -s "$BASE_URL/v1/unstructured-data/tenant-id" -H "X-Tenant-Id: tenant-b" | jq .
+echo ""
+
+# Test 18: Update clearing the required data field with null
+echo "18. Testing null data on update:"
+curl -s -X PATCH "$BASE_URL/v1/unstructured-data/valid-id" \
+  -H "Content-Type: application/json" \
+  -d '{"data": null}' | jq .
 echo ""
 
 echo "Error handling tests completed!" 
\ No newline at end of file
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// tracerName is the instrumentation scope of the spans of the server
+	tracerName = "protobuf-http-golang/server"
+	// serviceName is the service.name resource attribute, unless OTEL_SERVICE_NAME overrides it
+	serviceName = "discover-service"
+)
+
+// TraceExporter selects where spans are exported
+type TraceExporter string
+
+const (
+	// TraceExporterNone records no spans; trace context is still propagated
+	TraceExporterNone TraceExporter = "none"
+	// TraceExporterOTLP sends spans to an OTLP/gRPC collector
+	TraceExporterOTLP TraceExporter = "otlp"
+	// TraceExporterStdout writes spans to stdout as JSON
+	TraceExporterStdout TraceExporter = "stdout"
+	// TraceExporterFile appends spans to a file as JSON
+	TraceExporterFile TraceExporter = "file"
+)
+
+// parseTraceExporter parses a trace exporter name
+func parseTraceExporter(name string) (TraceExporter, error) {
+	switch exporter := TraceExporter(strings.ToLower(name)); exporter {
+	case TraceExporterNone, TraceExporterOTLP, TraceExporterStdout, TraceExporterFile:
+		return exporter, nil
+	default:
+		return "", fmt.Errorf("unknown trace exporter %q (want %q, %q, %q or %q)", name, TraceExporterNone, TraceExporterOTLP, TraceExporterStdout, TraceExporterFile)
+	}
+}
+
+// setupTracing installs the W3C Trace Context propagator and the tracer provider configured by cfg.
+// The returned function flushes the spans still buffered and stops the exporter.
+func setupTracing(ctx context.Context, cfg *Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	var err error
+	switch cfg.TraceExporter {
+	case TraceExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case TraceExporterOTLP:
+		// The endpoint and TLS settings default to the OTEL_EXPORTER_OTLP_* environment variables
+		var opts []otlptracegrpc.Option
+		if cfg.TraceOTLPEndpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.TraceOTLPEndpoint))
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case TraceExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case TraceExporterFile:
+		if file, err = os.OpenFile(cfg.TraceFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			return nil, fmt.Errorf("open trace file: %w", err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.TraceExporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s trace exporter: %w", cfg.TraceExporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TraceSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			err = errors.Join(err, file.Close())
+		}
+		return err
+	}, nil
+}
+
+// tracer returns the tracer of the server from the global tracer provider
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// metadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+// Get implements propagation.TextMapCarrier
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set implements propagation.TextMapCarrier
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys implements propagation.TextMapCarrier
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// TracingMiddleware records a server span for each HTTP request, continuing the trace of the
+// traceparent and tracestate headers. RouteMiddleware names the span after the matched route.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("user_agent.original", r.UserAgent()),
+			),
+		)
+		defer span.End()
+		if requestID := requestIDFromContext(ctx); requestID != "" {
+			span.SetAttributes(attribute.String("request.id", requestID))
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", recorder.status()))
+		if recorder.status() >= http.StatusInternalServerError {
+			span.SetStatus(otelcodes.Error, http.StatusText(recorder.status()))
+		}
+	})
+}
+
+// rpcAttributes describes a call to a full gRPC method name such as /package.Service/Method
+func rpcAttributes(fullMethod string) []attribute.KeyValue {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return []attribute.KeyValue{
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", method),
+	}
+}
+
+// endRPCSpan records the outcome of a call on its span. As for HTTP, only the codes of
+// server errors mark the span as failed.
+func endRPCSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	if err != nil {
+		span.RecordError(err)
+		if grpcStatusToHTTPStatus(code) >= http.StatusInternalServerError {
+			span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+		}
+	}
+	span.End()
+}
+
+// UnaryTracingInterceptor records a server span for each call. Calls from the in-process gateway
+// are children of the span of their HTTP request; other calls continue the trace of their
+// traceparent and tracestate metadata. It must run right after UnaryRequestIDInterceptor.
+func UnaryTracingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			md, _ := metadata.FromIncomingContext(ctx)
+			ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+		}
+		attrs := append(rpcAttributes(info.FullMethod), attribute.String("request.id", requestIDFromContext(ctx)))
+		ctx, span := tracer().Start(ctx, strings.TrimPrefix(info.FullMethod, "/"),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+
+		resp, err := handler(ctx, req)
+		endRPCSpan(span, err)
+		return resp, err
+	}
+}
+
+// UnaryClientTracingInterceptor records a client span for each call the gateway makes in endpoint
+// mode and sends its trace context in the traceparent and tracestate metadata, replacing the
+// headers the gateway forwarded from the HTTP request
+func UnaryClientTracingInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := tracer().Start(ctx, strings.TrimPrefix(method, "/"),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(rpcAttributes(method)...),
+		)
+
+		md, _ := metadata.FromOutgoingContext(ctx)
+		md = md.Copy()
+		otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+
+		err := invoker(metadata.NewOutgoingContext(ctx, md), method, req, reply, cc, opts...)
+		endRPCSpan(span, err)
+		return err
+	}
+}
+
+// tracedStore records a client span for each operation of a Store
+type tracedStore struct {
+	Store
+	kind StoreKind
+}
+
+// newTracedStore wraps store with spans named after the store kind and operation
+func newTracedStore(store Store, kind StoreKind) Store {
+	return &tracedStore{Store: store, kind: kind}
+}
+
+// start starts the span of an operation on the records of tenant
+func (s *tracedStore) start(ctx context.Context, operation, tenant string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "store."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", string(s.kind)),
+			attribute.String("db.operation.name", operation),
+			attribute.String("tenant", tenant),
+		),
+	)
+}
+
+// end records the outcome of an operation. Missing and existing records, and the status errors
+// of Update callbacks, are answers rather than failures of the store.
+func (s *tracedStore) end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		_, isStatus := status.FromError(err)
+		if !isStatus && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrAlreadyExists) {
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+// Create implements Store
+func (s *tracedStore) Create(ctx context.Context, record *Record) error {
+	ctx, span := s.start(ctx, "Create", record.Tenant)
+	err := s.Store.Create(ctx, record)
+	s.end(span, err)
+	return err
+}
+
+// Get implements Store
+func (s *tracedStore) Get(ctx context.Context, tenant, id string) (*Record, error) {
+	ctx, span := s.start(ctx, "Get", tenant)
+	record, err := s.Store.Get(ctx, tenant, id)
+	s.end(span, err)
+	return record, err
+}
+
+// List implements Store
+func (s *tracedStore) List(ctx context.Context, tenant, afterID string, limit int) ([]*Record, error) {
+	ctx, span := s.start(ctx, "List", tenant)
+	records, err := s.Store.List(ctx, tenant, afterID, limit)
+	span.SetAttributes(attribute.Int("db.response.returned_rows", len(records)))
+	s.end(span, err)
+	return records, err
+}
+
+// Update implements Store
+func (s *tracedStore) Update(ctx context.Context, tenant, id string, fn func(record *Record) error) (*Record, error) {
+	ctx, span := s.start(ctx, "Update", tenant)
+	record, err := s.Store.Update(ctx, tenant, id, fn)
+	s.end(span, err)
+	return record, err
+}
+
+// Delete implements Store
+func (s *tracedStore) Delete(ctx context.Context, tenant, id string) error {
+	ctx, span := s.start(ctx, "Delete", tenant)
+	err := s.Store.Delete(ctx, tenant, id)
+	s.end(span, err)
+	return err
+}